@@ -1,8 +1,11 @@
 package main
 
 import (
+	"os"
+
 	"github.com/josh5276/halp/core"
 	"github.com/josh5276/halp/core/keyring"
+	"github.com/josh5276/halp/plugins/cache"
 	"github.com/josh5276/halp/plugins/jira"
 	"github.com/josh5276/halp/plugins/version"
 	"github.com/sirupsen/logrus"
@@ -15,13 +18,17 @@ func main() {
 	// default store location (homedir/.config/gokeys)
 	cfg, err := keyring.New(logrus.Debug)
 	if err != nil {
-		logrus.Fatalf("halp.keyring.New:%s", err)
+		logrus.Errorf("halp.keyring.New:%s", err)
+		os.Exit(int(core.ExitConfig))
 	}
 
 	// Run a check of the current version. This will only alert and perform
-	// a check against artifactory every 2 hours.
-	if err := version.Check(cfg, buildVersion); err != nil {
-		logrus.Warning(err)
+	// a check against artifactory every 2 hours. It is skipped when the output
+	// is meant for another program, such as -o json or a completion script.
+	if !core.MachineReadable(os.Args) {
+		if err := version.Check(cfg, buildVersion, os.Stderr); err != nil {
+			logrus.Warning(err)
+		}
 	}
 
 	// Create a new cli parser and register all the plugins to be used.
@@ -29,11 +36,12 @@ func main() {
 	// when called.
 	parser := core.NewParser(
 		jira.Plugin,
+		cache.Plugin,
 		version.Plugin,
 	)
 
 	// Run the parser to parse all the arguments defined by halp and
 	// the additional plugins. This will also check if and what argument happened
-	// and execute the defined plugin function.
-	parser.Run(buildVersion, cfg)
+	// and execute the defined plugin function, exiting with the code it maps to.
+	os.Exit(int(parser.Run(buildVersion, cfg)))
 }
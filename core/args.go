@@ -0,0 +1,113 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Args are the positional arguments of a plugin, such as the issue key and
+// duration of `halp jira log NTC-1 1h30m`. argparse only knows flags, so the
+// parser takes the positional arguments off the command line before handing
+// it over; they have to come straight after the command, before any flag.
+type Args struct {
+	names  []string
+	values []string
+}
+
+// Positional declares the positional arguments of a plugin by the names shown
+// in its usage. Names in brackets, e.g. [DESCRIPTION], are optional and have
+// to come after the required ones. Set the result on Plugin.Args.
+func Positional(names ...string) *Args {
+	return &Args{names: names}
+}
+
+// Get returns the i-th positional argument, or "" when it was not given.
+func (a *Args) Get(i int) string {
+	if i < len(a.values) {
+		return a.values[i]
+	}
+	return ""
+}
+
+// Len returns the number of positional arguments given.
+func (a *Args) Len() int {
+	return len(a.values)
+}
+
+// Usage returns the names of the arguments, e.g. "ISSUE DURATION [DESCRIPTION]".
+func (a *Args) Usage() string {
+	return strings.Join(a.names, " ")
+}
+
+// set checks and stores the values given on the command line.
+func (a *Args) set(values []string) error {
+	required := 0
+	for _, name := range a.names {
+		if !strings.HasPrefix(name, "[") {
+			required++
+		}
+	}
+	if len(values) < required {
+		return fmt.Errorf("%s is required, usage: %s", a.names[len(values)], a.Usage())
+	}
+	if len(values) > len(a.names) {
+		return fmt.Errorf("unexpected argument %q, usage: %s", values[len(a.names)], a.Usage())
+	}
+	a.values = values
+	return nil
+}
+
+// positionals takes the positional arguments of the plugin being called off
+// the command line, leaving the command names and flags for argparse. Flags
+// may come before or between the commands, e.g. `halp -o json jira view KEY`,
+// they are moved after them since argparse only finds commands up front.
+func (p *Parser) positionals(osArgs []string) ([]string, error) {
+	var (
+		plugins  = p.Plugins
+		last     *Plugin
+		commands = make(map[int]bool)
+		start    = 1
+	)
+	for i := 1; i < len(osArgs); i++ {
+		if next := find(plugins, osArgs[i]); next != nil {
+			last, plugins, start = next, next.Children, i+1
+			commands[i] = true
+			continue
+		}
+		if !isFlag(osArgs[i]) {
+			break
+		}
+		// Skip the value of the flag along with it, unless it was given as
+		// --flag=value or the next word is a flag or command of its own.
+		if !strings.Contains(osArgs[i], "=") && i+1 < len(osArgs) &&
+			!isFlag(osArgs[i+1]) && find(plugins, osArgs[i+1]) == nil {
+			i++
+		}
+	}
+
+	end := start
+	if last != nil && last.Args != nil {
+		for end < len(osArgs) && !isFlag(osArgs[end]) {
+			end++
+		}
+		if err := last.Args.set(append([]string{}, osArgs[start:end]...)); err != nil {
+			return osArgs, err
+		}
+	}
+	args, rest := osArgs[:1:1], make([]string, 0, len(osArgs))
+	for i := 1; i < len(osArgs); i++ {
+		switch {
+		case commands[i]:
+			args = append(args, osArgs[i])
+		case i < start || i >= end:
+			rest = append(rest, osArgs[i])
+		}
+	}
+	return append(args, rest...), nil
+}
+
+// isFlag reports whether a word of the command line is a flag, "-" being the
+// argument that stands for stdin.
+func isFlag(arg string) bool {
+	return arg != "-" && strings.HasPrefix(arg, "-")
+}
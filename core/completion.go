@@ -0,0 +1,352 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/jokelyo/argparse"
+)
+
+type (
+	// Completer returns the candidate values for a flag or the positional
+	// arguments of a command when the shell asks for them, e.g. project keys
+	// for --project.
+	Completer func(env Env) ([]string, error)
+
+	// completionNode is a single command in the tree handed to the shell
+	// templates. Path is the space separated command chain starting with
+	// the app name.
+	completionNode struct {
+		Path     string
+		Commands []completionItem
+		Flags    []completionItem
+		Dynamic  bool
+	}
+
+	// completionItem is a subcommand or flag name with its help text.
+	completionItem struct {
+		Name    string
+		Desc    string
+		Dynamic bool
+	}
+)
+
+// completers holds the dynamic completion sources keyed by the long name of
+// the flag they complete.
+var completers = make(map[string]Completer)
+
+// CompleteFlag registers fn as the source of dynamic completions for every
+// flag with the long name passed in. The generated scripts call back into
+// `halp completion values` to fetch the values when the flag is completed.
+func CompleteFlag(name string, fn Completer) {
+	completers[name] = fn
+}
+
+// completionPlugin registers the built-in `completion` command. It holds a
+// reference to the parser so that the scripts reflect every plugin that was
+// registered, including the ones registered after it.
+func completionPlugin(p *Parser) Register {
+	return func(parent *argparse.Command) Plugin {
+		cmd := parent.NewCommand("completion", "Generate shell completion scripts.")
+		children := make([]Plugin, 0)
+		for _, shell := range []string{"bash", "zsh", "fish"} {
+			shell := shell
+			children = append(children, Plugin{
+				CMD: cmd.NewCommand(shell, fmt.Sprintf("Print the %s completion script.", shell)),
+				Func: func(_ context.Context, env Env) error {
+					return p.Completion(env.Stdout, shell)
+				},
+			})
+		}
+
+		// values is hidden from the usage output; only the generated
+		// scripts call it, with either the flag or the command being completed.
+		values := cmd.NewCommand("values", argparse.DisableDescription)
+		flag := values.String("", "flag", &argparse.Options{Help: argparse.DisableDescription})
+		command := values.String("", "command", &argparse.Options{Help: argparse.DisableDescription})
+		children = append(children, Plugin{
+			CMD: values,
+			Func: func(_ context.Context, env Env) error {
+				if *command != "" {
+					return p.completeArgs(env, *command)
+				}
+				return completeValues(env, *flag)
+			},
+		})
+		return Plugin{CMD: cmd, Children: children}
+	}
+}
+
+// Completion writes the completion script for the shell passed in (bash,
+// zsh or fish) to w.
+func (p *Parser) Completion(w io.Writer, shell string) error {
+	tmpl, ok := completionTemplates[shell]
+	if !ok {
+		return Errorf(ExitUsage, "completion: unsupported shell %q", shell)
+	}
+	return tmpl.Execute(w, struct {
+		App   string
+		Nodes []completionNode
+	}{AppName, p.completionTree()})
+}
+
+// completionTree flattens the plugin tree into one node per command. Flags
+// of parent commands are repeated on their children, since argparse accepts
+// them anywhere below the command that declared them.
+func (p *Parser) completionTree() []completionNode {
+	root := completionNode{
+		Path:     AppName,
+		Commands: completionCommands(p.Plugins),
+		Flags:    completionFlags(&p.Parser.Command),
+	}
+	nodes := []completionNode{root}
+	flags := map[string][]completionItem{AppName: root.Flags}
+
+	p.Walk(func(path []string, plugin Plugin) {
+		if plugin.CMD.GetDescription() == argparse.DisableDescription {
+			return
+		}
+		parent := strings.Join(append([]string{AppName}, path[:len(path)-1]...), " ")
+		node := completionNode{
+			Path:     parent + " " + plugin.Name(),
+			Commands: completionCommands(plugin.Children),
+			Flags:    append(completionFlags(plugin.CMD), flags[parent]...),
+			Dynamic:  plugin.Complete != nil,
+		}
+		flags[node.Path] = node.Flags
+		nodes = append(nodes, node)
+	})
+	return nodes
+}
+
+func completionCommands(plugins []Plugin) []completionItem {
+	items := make([]completionItem, 0, len(plugins))
+	for _, plugin := range plugins {
+		if plugin.CMD.GetDescription() == argparse.DisableDescription {
+			continue
+		}
+		items = append(items, completionItem{Name: plugin.Name(), Desc: plugin.CMD.GetDescription()})
+	}
+	return items
+}
+
+func completionFlags(cmd *argparse.Command) []completionItem {
+	items := make([]completionItem, 0)
+	for _, arg := range cmd.GetArgs() {
+		var help string
+		if arg.GetOpts() != nil {
+			help = arg.GetOpts().Help
+		}
+		if help == argparse.DisableDescription {
+			continue
+		}
+		_, dynamic := completers[arg.GetLname()]
+		items = append(items, completionItem{Name: "--" + arg.GetLname(), Desc: help, Dynamic: dynamic})
+		if arg.GetSname() != "" {
+			items = append(items, completionItem{Name: "-" + arg.GetSname(), Desc: help})
+		}
+	}
+	return items
+}
+
+// completeValues prints the dynamic values registered for a flag, one per
+// line. Unknown flags print nothing so the shell falls back to its default.
+func completeValues(env Env, flag string) error {
+	return printValues(env, completers[strings.TrimLeft(flag, "-")])
+}
+
+// completeArgs prints the values the positional arguments of the command are
+// completed with, the command being its path without the app name, e.g.
+// "jira search".
+func (p *Parser) completeArgs(env Env, command string) error {
+	var fn Completer
+	p.Walk(func(path []string, plugin Plugin) {
+		if strings.Join(path, " ") == command {
+			fn = plugin.Complete
+		}
+	})
+	return printValues(env, fn)
+}
+
+// printValues prints the values of a completer, one per line, or nothing
+// when there is none.
+func printValues(env Env, fn Completer) error {
+	if fn == nil {
+		return nil
+	}
+	values, err := fn(env)
+	if err != nil {
+		return err
+	}
+	sort.Strings(values)
+	for _, v := range values {
+		fmt.Fprintln(env.Stdout, v)
+	}
+	return nil
+}
+
+// shellQuote single-quotes s for bash and zsh.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// fishQuote single-quotes s for fish, which escapes quotes with a backslash.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// zshSpec escapes the colons _describe uses to split name from description.
+func zshSpec(item completionItem) string {
+	return shellQuote(strings.Replace(item.Name, ":", `\:`, -1) + ":" + item.Desc)
+}
+
+func dynamicFlags(nodes []completionNode) []string {
+	seen := make(map[string]bool)
+	flags := make([]string, 0)
+	for _, node := range nodes {
+		for _, flag := range node.Flags {
+			if flag.Dynamic && !seen[flag.Name] {
+				seen[flag.Name] = true
+				flags = append(flags, flag.Name)
+			}
+		}
+	}
+	return flags
+}
+
+var completionFuncs = template.FuncMap{
+	"quote":   shellQuote,
+	"fish":    fishQuote,
+	"zsh":     zshSpec,
+	"dynamic": dynamicFlags,
+	"command": func(path string) string { return strings.TrimPrefix(path, AppName+" ") },
+	"words": func(node completionNode) string {
+		names := make([]string, 0, len(node.Commands)+len(node.Flags))
+		for _, item := range append(node.Commands, node.Flags...) {
+			names = append(names, item.Name)
+		}
+		return strings.Join(names, " ")
+	},
+}
+
+var completionTemplates = map[string]*template.Template{
+	"bash": template.Must(template.New("bash").Funcs(completionFuncs).Parse(bashCompletion)),
+	"zsh":  template.Must(template.New("zsh").Funcs(completionFuncs).Parse(zshCompletion)),
+	"fish": template.Must(template.New("fish").Funcs(completionFuncs).Parse(fishCompletion)),
+}
+
+const bashCompletion = `# bash completion for {{.App}}
+# Install with: {{.App}} completion bash > /etc/bash_completion.d/{{.App}}
+
+_{{.App}}() {
+    local cur prev cmdpath word i words
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+    cmdpath="{{.App}}"
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        word="${COMP_WORDS[i]}"
+        case "${cmdpath} ${word}" in
+{{- range .Nodes}}{{if ne .Path $.App}}
+            {{quote .Path}}) cmdpath="${cmdpath} ${word}" ;;
+{{- end}}{{end}}
+        esac
+    done
+
+    case "${prev}" in
+{{- range dynamic .Nodes}}
+        {{.}})
+            COMPREPLY=($(compgen -W "$({{$.App}} completion values --flag {{.}} 2>/dev/null)" -- "${cur}"))
+            return
+            ;;
+{{- end}}
+    esac
+
+    case "${cmdpath}" in
+{{- range .Nodes}}
+        {{quote .Path}}) words={{quote (words .)}}
+{{- if .Dynamic}}" $({{$.App}} completion values --command {{quote (command .Path)}} 2>/dev/null)"{{end}} ;;
+{{- end}}
+    esac
+    COMPREPLY=($(compgen -W "${words}" -- "${cur}"))
+}
+
+complete -F _{{.App}} {{.App}}
+`
+
+const zshCompletion = `#compdef {{.App}}
+# zsh completion for {{.App}}
+# Install with: {{.App}} completion zsh > "${fpath[1]}/_{{.App}}"
+
+_{{.App}}() {
+    local cmdpath word i
+    local -a cmds flags
+    cmdpath="{{.App}}"
+    for ((i = 2; i < CURRENT; i++)); do
+        word="${words[i]}"
+        case "${cmdpath} ${word}" in
+{{- range .Nodes}}{{if ne .Path $.App}}
+            {{quote .Path}}) cmdpath="${cmdpath} ${word}" ;;
+{{- end}}{{end}}
+        esac
+    done
+
+    case "${words[CURRENT-1]}" in
+{{- range dynamic .Nodes}}
+        {{.}})
+            compadd -- ${(f)"$({{$.App}} completion values --flag {{.}} 2>/dev/null)"}
+            return
+            ;;
+{{- end}}
+    esac
+
+    case "${cmdpath}" in
+{{- range .Nodes}}
+        {{quote .Path}})
+            cmds=({{range .Commands}} {{zsh .}}{{end}} )
+            flags=({{range .Flags}} {{zsh .}}{{end}} )
+{{- if .Dynamic}}
+            cmds+=(${(f)"$({{$.App}} completion values --command {{quote (command .Path)}} 2>/dev/null)"})
+{{- end}}
+            ;;
+{{- end}}
+    esac
+    _describe -t commands 'command' cmds
+    _describe -t options 'option' flags
+}
+
+compdef _{{.App}} {{.App}}
+`
+
+const fishCompletion = `# fish completion for {{.App}}
+# Install with: {{.App}} completion fish > ~/.config/fish/completions/{{.App}}.fish
+
+function __{{.App}}_path
+    set -l cmdpath {{.App}}
+    for word in (commandline -opc)[2..-1]
+        switch "$cmdpath $word"
+            case{{range .Nodes}}{{if ne .Path $.App}} {{fish .Path}}{{end}}{{end}}
+                set cmdpath "$cmdpath $word"
+        end
+    end
+    test "$cmdpath" = "$argv[1]"
+end
+
+complete -c {{.App}} -f
+{{- range .Nodes}}{{$path := .Path}}
+{{- range .Commands}}
+complete -c {{$.App}} -n {{fish (printf "__%s_path %s" $.App (quote $path))}} -a {{fish .Name}} -d {{fish .Desc}}
+{{- end}}
+{{- if .Dynamic}}
+complete -c {{$.App}} -n {{fish (printf "__%s_path %s" $.App (quote $path))}} -a {{fish (printf "(%s completion values --command %s 2>/dev/null)" $.App (quote (command $path)))}}
+{{- end}}
+{{- range .Flags}}
+complete -c {{$.App}} -n {{fish (printf "__%s_path %s" $.App (quote $path))}}
+{{- if eq (len .Name) 2}} -s {{slice .Name 1}}{{else}} -l {{slice .Name 2}}{{end}} -d {{fish .Desc}}
+{{- if .Dynamic}} -r -a {{fish (printf "(%s completion values --flag %s 2>/dev/null)" $.App .Name)}}{{end}}
+{{- end}}
+{{- end}}
+`
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParser_Completion(t *testing.T) {
+	CompleteFlag("project", func(Env) ([]string, error) { return []string{"ABC"}, nil })
+	defer delete(completers, "project")
+
+	p := testParser()
+	p.Plugins[0].CMD.String("", "project", nil)
+	p.Plugins[0].Children[0].Complete = func(Env) ([]string, error) { return []string{"@mine", "@all"}, nil }
+
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		var buf bytes.Buffer
+		if err := p.Completion(&buf, shell); err != nil {
+			t.Fatalf("ERROR: Completion(%s):%s", shell, err)
+		}
+		for _, want := range []string{"halp outer inner", "deep", "--project", "completion values --flag --project",
+			"completion values --command", "outer leaf"} {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("ERROR: %s script is missing %q", shell, want)
+			}
+		}
+		if strings.Contains(buf.String(), "'halp completion values'") {
+			t.Errorf("ERROR: %s script exposes the hidden values command", shell)
+		}
+	}
+	if err := p.Completion(&bytes.Buffer{}, "tcsh"); Code(err) != ExitUsage {
+		t.Fatalf("ERROR: expected a usage error for an unknown shell, got %v", err)
+	}
+	t.Log("SUCCESS: generated bash, zsh and fish completion scripts")
+}
+
+func TestParser_completionValues(t *testing.T) {
+	CompleteFlag("project", func(Env) ([]string, error) { return []string{"OPS", "NTC"}, nil })
+	defer delete(completers, "project")
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--flag", "--project"}, "NTC\nOPS\n"},
+		{[]string{"--flag", "--unknown"}, ""},
+		{[]string{"--command", "outer leaf"}, "@all\n@mine\n"},
+		{[]string{"--command", "outer inner deep"}, ""},
+	}
+	for _, test := range tests {
+		p := testParser()
+		p.Plugins[0].Children[0].Complete = func(Env) ([]string, error) { return []string{"@mine", "@all"}, nil }
+		var out bytes.Buffer
+		err := p.Execute(context.Background(), append([]string{"halp", "completion", "values"}, test.args...), Env{Stdout: &out})
+		if err != nil || out.String() != test.want {
+			t.Errorf("ERROR: values %v printed %q, expected %q (%v)", test.args, out.String(), test.want, err)
+		}
+	}
+	t.Logf("SUCCESS: completed %d flags and commands", len(tests))
+}
@@ -0,0 +1,12 @@
+package core
+
+import "github.com/josh5276/halp/shared/atlassian"
+
+// Atlassian creates the client of the JIRA instance and Tempo account in the
+// settings, with Connect when it is set.
+func (e *Env) Atlassian() (*atlassian.Client, error) {
+	if e.Connect != nil {
+		return e.Connect(&e.Settings)
+	}
+	return atlassian.FromSettings(&e.Settings)
+}
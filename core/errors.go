@@ -0,0 +1,82 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ExitCode is the status halp hands back to the shell once a plugin
+// has finished running.
+type ExitCode int
+
+const (
+	// ExitOK is returned when the plugin completed without error.
+	ExitOK ExitCode = 0
+	// ExitError is the catch-all code for a plugin that failed.
+	ExitError ExitCode = 1
+	// ExitUsage is returned when the arguments could not be parsed or
+	// were incomplete.
+	ExitUsage ExitCode = 2
+	// ExitConfig is returned when the settings or credentials could not
+	// be loaded.
+	ExitConfig ExitCode = 3
+	// ExitAPI is returned when a remote API rejected or failed a request.
+	ExitAPI ExitCode = 4
+	// ExitCancelled is returned when the user interrupted halp (128+SIGINT).
+	ExitCancelled ExitCode = 130
+)
+
+// Error is the typed error a plugin returns to the parser. It pairs the
+// underlying error with the exit code halp should terminate with.
+type Error struct {
+	Code ExitCode
+	Err  error
+}
+
+// Error satisfies the error interface.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap exposes the underlying error to errors.Is and errors.As.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// usageError marks an error that has already been printed along with the
+// usage, so that Run doesn't log it a second time.
+type usageError struct {
+	error
+}
+
+// Errorf formats an error the same way fmt.Errorf does and tags it with
+// the exit code passed in.
+func Errorf(code ExitCode, format string, a ...interface{}) error {
+	return &Error{Code: code, Err: fmt.Errorf(format, a...)}
+}
+
+// WithCode tags an existing error with an exit code. A nil error stays nil
+// so that it can wrap a return value directly.
+func WithCode(code ExitCode, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// Code maps an error returned from a plugin to the exit code halp should
+// return. Errors that were not tagged fall back to ExitError.
+func Code(err error) ExitCode {
+	if err == nil {
+		return ExitOK
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	if errors.Is(err, context.Canceled) {
+		return ExitCancelled
+	}
+	return ExitError
+}
@@ -0,0 +1,30 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCode(t *testing.T) {
+	var tests = []struct {
+		err  error
+		want ExitCode
+	}{
+		{nil, ExitOK},
+		{errors.New("plain"), ExitError},
+		{Errorf(ExitConfig, "cfg:%s", "missing"), ExitConfig},
+		{fmt.Errorf("wrapped:%w", WithCode(ExitAPI, errors.New("400"))), ExitAPI},
+		{fmt.Errorf("wrapped:%w", context.Canceled), ExitCancelled},
+	}
+	for _, tc := range tests {
+		if got := Code(tc.err); got != tc.want {
+			t.Errorf("ERROR: Code(%v) = %d, expected %d", tc.err, got, tc.want)
+		}
+	}
+	if WithCode(ExitAPI, nil) != nil {
+		t.Fatal("ERROR: WithCode should keep a nil error nil")
+	}
+	t.Logf("SUCCESS: mapped %d errors to exit codes", len(tests))
+}
@@ -3,8 +3,10 @@ package keyring
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-ini/ini"
 	"github.com/josh5276/keyring"
@@ -23,6 +25,7 @@ type Settings struct {
 	User         string
 	JIRAInstance string
 	JIRAUser     string
+	Timezone     string
 	Key          map[Service]keyring.Keyring
 	pin          int
 	File         *ini.File
@@ -118,6 +121,12 @@ func (s *Settings) loadBaseSection(cfg *ini.File) error {
 	}
 	s.JIRAUser = jiraUser.String()
 
+	// The timezone is optional and never prompted for, most users will want
+	// the timezone of the machine halp runs on.
+	if sec.HasKey("timezone") {
+		s.Timezone = sec.Key("timezone").String()
+	}
+
 	// If we are using a supported keyring backend, then we don't need to set
 	// a pin.
 	for _, backend := range keyring.AvailableBackends() {
@@ -150,6 +159,83 @@ func (s *Settings) getPin(sec *ini.Section) error {
 	return nil
 }
 
+// Value returns the value of an optional key within a section of the settings
+// file, or "" when either does not exist. Unlike ini.Section.Key it never creates
+// the key, so reading an optional setting doesn't write it back to the file.
+func (s Settings) Value(section, key string) string {
+	if s.File == nil {
+		return ""
+	}
+	sec, err := s.File.GetSection(section)
+	if err != nil || !sec.HasKey(key) {
+		return ""
+	}
+	return strings.TrimSpace(sec.Key(key).String())
+}
+
+// Values splits a comma separated optional key into its trimmed, non-empty
+// values. See Value.
+func (s Settings) Values(section, key string) []string {
+	values := make([]string, 0)
+	for _, v := range strings.Split(s.Value(section, key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Keys returns the names of the keys within an optional section of the settings
+// file, in the order they were written, or nothing when the section does not exist.
+func (s Settings) Keys(section string) []string {
+	if s.File == nil {
+		return nil
+	}
+	sec, err := s.File.GetSection(section)
+	if err != nil {
+		return nil
+	}
+	return sec.KeyStrings()
+}
+
+// Sections returns the names of the sections of the settings file starting with
+// prefix, e.g. every issue.template.* section, with the prefix trimmed off.
+func (s Settings) Sections(prefix string) []string {
+	names := make([]string, 0)
+	if s.File == nil {
+		return names
+	}
+	for _, name := range s.File.SectionStrings() {
+		if strings.HasPrefix(name, prefix) && name != prefix {
+			names = append(names, strings.TrimPrefix(name, prefix))
+		}
+	}
+	return names
+}
+
+// Dir returns the directory holding the settings file, where halp keeps the
+// rest of its local state. It is "" when the settings were not loaded from a file.
+func (s Settings) Dir() string {
+	if s.Source == "" {
+		return ""
+	}
+	return filepath.Dir(s.Source)
+}
+
+// Location returns the timezone set with the optional `timezone` key in the
+// base section (an IANA name such as America/Chicago), falling back to the
+// local timezone of the machine.
+func (s Settings) Location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("timezone:%s", err)
+	}
+	return loc, nil
+}
+
 // prompt is a simple helper function to prompt for missing
 // config data.
 func prompt(text string) string {
@@ -3,14 +3,20 @@
 package core
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"strings"
 	"syscall"
 
 	"github.com/gookit/color"
 	"github.com/jokelyo/argparse"
 	"github.com/josh5276/halp/core/keyring"
+	"github.com/josh5276/halp/shared"
+	"github.com/josh5276/halp/shared/atlassian"
 	"github.com/sirupsen/logrus"
 )
 
@@ -21,18 +27,53 @@ type (
 		*argparse.Parser
 		Plugins []Plugin
 	}
-	// Plugin is the command and calling function for each plugin
+	// Plugin is the command and calling function for each plugin. A plugin
+	// with Children is a group; its Func is optional and only runs when the
+	// group is called without one of its subcommands.
+	// Args, when set, are the positional arguments the plugin takes, and
+	// Complete the values the shell completes them with.
 	Plugin struct {
-		CMD  *argparse.Command
-		Func func(keyring.Settings)
+		CMD      *argparse.Command
+		Func     Func
+		Children []Plugin
+		Args     *Args
+		Complete Completer
+	}
+
+	// Register is the constructor every plugin exposes. It creates the
+	// plugin's command underneath the parent command passed in, so that the
+	// same plugin can be registered at the top level or nested in a Group.
+	Register func(parent *argparse.Command) Plugin
+
+	// Func is the signature every plugin implements. The context is cancelled
+	// when the user interrupts halp, and the returned error decides the exit
+	// code (see Code).
+	Func func(ctx context.Context, env Env) error
+
+	// Env is the environment a plugin runs in: the loaded settings along with
+	// the streams it should read from and write to, rather than reaching for
+	// the os package directly.
+	Env struct {
+		Settings keyring.Settings
+		Output   shared.Format
+		Stdin    io.Reader
+		Stdout   io.Writer
+		Stderr   io.Writer
+
+		// Connect creates the Atlassian client from the settings, it is
+		// atlassian.FromSettings when nil. The tests point it at a fake server.
+		Connect func(*keyring.Settings) (*atlassian.Client, error)
 	}
 )
 
-var debugFlag *bool
+var (
+	debugFlag  *bool
+	outputFlag *string
+)
 
 // NewParser function will initiate and return the parent parser for the
 // halp app.
-func NewParser(fn ...func(*Parser) Plugin) Parser {
+func NewParser(fn ...Register) Parser {
 	// Create new main parser object
 	p := Parser{
 		Parser:  argparse.NewParser(AppName, "Please Halp me! Basic CLI tool to run quick functions."),
@@ -41,33 +82,216 @@ func NewParser(fn ...func(*Parser) Plugin) Parser {
 
 	// Define the top-level arguments pinned to the halp parser.
 	debugFlag = p.Flag("", "debug", &argparse.Options{Help: "view debug level logging"})
+	outputFlag = p.Selector("o", "output", shared.Formats, &argparse.Options{
+		Help:    "output format for reports: " + strings.Join(shared.Formats, ", "),
+		Default: string(shared.FormatTable),
+	})
 
-	// Register the plugin commands into the parser
-	for _, f := range fn {
-		p.Plugins = append(p.Plugins, f(&p))
-	}
+	// Register the plugin commands into the parser, followed by the
+	// built-in commands that introspect them.
+	p.Plugins = Nest(&p.Parser.Command, fn...)
+	p.Plugins = append(p.Plugins, completionPlugin(&p)(&p.Parser.Command))
 	return p
 }
 
+// Group returns a Register for a command that only holds other plugins, e.g.
+// `halp jira` holding `worklog` and `issue`. The parser dispatches straight to
+// the deepest command that happened, so groups need no dispatcher of their own.
+func Group(name, desc string, children ...Register) Register {
+	return func(parent *argparse.Command) Plugin {
+		cmd := parent.NewCommand(name, desc)
+		return Plugin{CMD: cmd, Children: Nest(cmd, children...)}
+	}
+}
+
+// Nest registers each of the children underneath cmd and returns the plugins
+// they created. Plugins that have their own Func as well as subcommands use
+// this to fill in Plugin.Children.
+func Nest(cmd *argparse.Command, children ...Register) []Plugin {
+	plugins := make([]Plugin, 0, len(children))
+	for _, register := range children {
+		plugins = append(plugins, register(cmd))
+	}
+	return plugins
+}
+
+// Name is the name of the command the plugin registered.
+func (p Plugin) Name() string {
+	return p.CMD.GetName()
+}
+
+// Walk calls fn for every plugin registered in the parser, parents before
+// their children. The path holds the command names leading up to and
+// including the plugin, without the halp app name.
+func (p *Parser) Walk(fn func(path []string, plugin Plugin)) {
+	walk(nil, p.Plugins, fn)
+}
+
+func walk(path []string, plugins []Plugin, fn func([]string, Plugin)) {
+	for _, plugin := range plugins {
+		current := append(path[:len(path):len(path)], plugin.Name())
+		fn(current, plugin)
+		walk(current, plugin.Children, fn)
+	}
+}
+
+// Happened returns the deepest plugin whose command happened on the command
+// line. The bool is false when no plugin command was given.
+func (p *Parser) Happened() (Plugin, bool) {
+	return deepest(p.Plugins)
+}
+
+func deepest(plugins []Plugin) (Plugin, bool) {
+	for _, plugin := range plugins {
+		if !plugin.CMD.Happened() {
+			continue
+		}
+		if child, ok := deepest(plugin.Children); ok {
+			return child, true
+		}
+		return plugin, true
+	}
+	return Plugin{}, false
+}
+
+// args prepares the command line for argparse. argparse rejects a command
+// with subcommands when nothing follows it, which would make a group's own
+// Func unreachable, so an empty (ignored) argument is appended in that case.
+func (p *Parser) args(osArgs []string) []string {
+	var (
+		plugins = p.Plugins
+		last    *Plugin
+	)
+	for _, arg := range osArgs[1:] {
+		if last = find(plugins, arg); last == nil {
+			// Anything other than a command name means argparse
+			// has arguments left to consume.
+			return osArgs
+		}
+		plugins = last.Children
+	}
+	if last != nil && last.Func != nil && len(last.Children) > 0 {
+		return append(osArgs[:len(osArgs):len(osArgs)], "")
+	}
+	return osArgs
+}
+
+func find(plugins []Plugin, name string) *Plugin {
+	for i := range plugins {
+		if plugins[i].Name() == name {
+			return &plugins[i]
+		}
+	}
+	return nil
+}
+
+// MachineReadable reports whether a command line, starting with the program name,
+// asks for output meant for another program: a completion script or values, or an
+// --output other than table. Nothing else may be printed to stdout around it.
+func MachineReadable(args []string) bool {
+	if len(args) > 1 && args[1] == "completion" {
+		return true
+	}
+	for i, arg := range args {
+		var value string
+		switch {
+		case arg == "-o" || arg == "--output":
+			if i+1 < len(args) {
+				value = args[i+1]
+			}
+		case strings.HasPrefix(arg, "--output="):
+			value = strings.TrimPrefix(arg, "--output=")
+		case strings.HasPrefix(arg, "-o") && !strings.HasPrefix(arg, "--"):
+			value = strings.TrimPrefix(strings.TrimPrefix(arg, "-o"), "=")
+		default:
+			continue
+		}
+		if value != "" && value != string(shared.FormatTable) {
+			return true
+		}
+	}
+	return false
+}
+
 // Run method will parse the arguments in the parser as well as range through all the
-// registered plugins to determine which action "Happened()"
-func (p *Parser) Run(version string, cfg keyring.Settings) {
+// registered plugins to determine which action "Happened()". The returned ExitCode
+// should be handed to os.Exit by the caller.
+func (p *Parser) Run(version string, cfg keyring.Settings) ExitCode {
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	env := Env{
+		Settings: cfg,
+		Stdin:    os.Stdin,
+		Stdout:   os.Stdout,
+		Stderr:   os.Stderr,
+	}
+	err := p.Execute(ctx, os.Args, env)
+	var usage *usageError
+	switch {
+	case err == nil:
+	case errors.As(err, &usage):
+		// The usage has already been printed.
+	case ctx.Err() == context.Canceled:
+		logrus.Debugf("%s: %s", getCommand(os.Args), err)
+		logrus.Warn("interrupted")
+		return ExitCancelled
+	default:
+		logrus.Error(err)
+	}
+	return Code(err)
+}
+
+// Execute parses args, a command line starting with the program name, and runs
+// the plugin that happened in env, filling in env.Output from the --output flag.
+// Usage errors are printed to env.Stdout along with the usage. Unlike Run it
+// leaves the process alone, so tests can drive halp end to end with it.
+func (p *Parser) Execute(ctx context.Context, args []string, env Env) error {
 	// Parse input
-	if err := p.Parse(os.Args); err != nil {
+	args, err := p.positionals(args)
+	if err == nil {
+		err = p.Parse(p.args(args))
+	}
+	if err != nil {
 		// In case of error print error and print usage
 		// This can also be done by passing -h or --help flags
-		fmt.Print(p.Usage(color.Red.Sprint(err)))
-		// abort if there is an error parsing arguments
-		syscall.Exit(1)
+		fmt.Fprint(env.Stdout, p.Usage(color.Red.Sprint(err)))
+		return WithCode(ExitUsage, &usageError{err})
 	}
 	if *debugFlag {
 		logrus.SetLevel(logrus.DebugLevel)
 	}
-	for _, v := range p.Plugins {
-		if v.CMD.Happened() {
-			v.Func(cfg)
-		}
+	env.Output = shared.Format(*outputFlag)
+
+	plugin, ok := p.Happened()
+	if !ok {
+		return nil
+	}
+	if plugin.Func == nil {
+		// A group was called without any of its subcommands.
+		fmt.Fprint(env.Stdout, plugin.CMD.Usage(color.Red.Sprint("a subcommand is required")))
+		return WithCode(ExitUsage, &usageError{errors.New("a subcommand is required")})
 	}
+	return plugin.Func(ctx, env)
+}
+
+// interruptContext returns a context that is cancelled the first time halp
+// receives an interrupt or termination signal, so that in-flight requests can
+// unwind instead of the process being killed mid-write.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		defer signal.Stop(sig)
+		select {
+		case s := <-sig:
+			logrus.Debugf("received %s, cancelling", s)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
 }
 
 func getCommand(args []string) string {
@@ -0,0 +1,156 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jokelyo/argparse"
+)
+
+func testLeaf(name string) Register {
+	return func(p *argparse.Command) Plugin {
+		return Plugin{
+			CMD:  p.NewCommand(name, "test leaf"),
+			Func: func(context.Context, Env) error { return nil },
+		}
+	}
+}
+
+// testDefault registers a plugin with its own Func as well as a child.
+func testDefault(p *argparse.Command) Plugin {
+	cmd := p.NewCommand("report", "test group with a default")
+	return Plugin{
+		CMD:      cmd,
+		Func:     func(context.Context, Env) error { return nil },
+		Children: Nest(cmd, testLeaf("list")),
+	}
+}
+
+func testParser() Parser {
+	return NewParser(
+		Group("outer", "test group",
+			testLeaf("leaf"),
+			Group("inner", "nested test group", testLeaf("deep")),
+			testDefault,
+		),
+		testLeaf("top"),
+	)
+}
+
+func TestParser_Happened(t *testing.T) {
+	var tests = map[string]string{
+		"halp top":                "top",
+		"halp outer leaf":         "leaf",
+		"halp outer inner deep":   "deep",
+		"halp outer report":       "report",
+		"halp outer report list":  "list",
+		"halp outer leaf --debug": "leaf",
+	}
+	for line, want := range tests {
+		p := testParser()
+		if err := p.Parse(p.args(strings.Fields(line))); err != nil {
+			t.Fatalf("ERROR: Parse(%s):%s", line, err)
+		}
+		plugin, ok := p.Happened()
+		if !ok || plugin.Name() != want {
+			t.Errorf("ERROR: %s dispatched to %q, expected %q", line, plugin.Name(), want)
+		}
+	}
+	t.Logf("SUCCESS: dispatched %d command lines", len(tests))
+}
+
+func TestParser_Walk(t *testing.T) {
+	p := testParser()
+	var paths []string
+	p.Walk(func(path []string, _ Plugin) {
+		paths = append(paths, strings.Join(path, " "))
+	})
+	expected := "outer,outer leaf,outer inner,outer inner deep,outer report,outer report list,top," +
+		"completion,completion bash,completion zsh,completion fish,completion values"
+	if strings.Join(paths, ",") != expected {
+		t.Fatalf("ERROR: walked %v, expected %s", paths, expected)
+	}
+	t.Logf("SUCCESS: walked %d plugins", len(paths))
+}
+
+func TestParser_Execute(t *testing.T) {
+	var tests = []struct {
+		line string
+		code ExitCode
+	}{
+		{"halp top -o json", ExitOK},
+		{"halp outer", ExitUsage},
+		{"halp top --bogus", ExitUsage},
+	}
+	for _, test := range tests {
+		p := testParser()
+		var out bytes.Buffer
+		err := p.Execute(context.Background(), strings.Fields(test.line), Env{Stdout: &out})
+		if code := Code(err); code != test.code {
+			t.Errorf("ERROR: %s exited with %d, expected %d (%v)", test.line, code, test.code, err)
+		}
+		if test.code == ExitUsage && !strings.Contains(out.String(), "usage:") {
+			t.Errorf("ERROR: %s did not print the usage, got %q", test.line, out.String())
+		}
+	}
+	t.Logf("SUCCESS: executed %d command lines", len(tests))
+}
+
+func TestParser_positionals(t *testing.T) {
+	var tests = []struct {
+		line string
+		want string
+		code ExitCode
+	}{
+		{"halp outer log NTC-1 1h", "NTC-1,1h,", ExitOK},
+		{"halp outer log NTC-1 1h30m - --debug", "NTC-1,1h30m,-", ExitOK},
+		{"halp outer log NTC-1", "", ExitUsage},
+		{"halp outer log NTC-1 1h desc extra", "", ExitUsage},
+		{"halp outer log --debug NTC-1 1h", "", ExitUsage},
+		{"halp -o json outer log NTC-1 1h", "NTC-1,1h,", ExitOK},
+		{"halp --debug outer log NTC-1 1h Review", "NTC-1,1h,Review", ExitOK},
+		{"halp outer --output json log NTC-1 1h", "NTC-1,1h,", ExitOK},
+		{"halp outer --output=csv log NTC-1 1h --debug", "NTC-1,1h,", ExitOK},
+	}
+	for _, test := range tests {
+		args := Positional("ISSUE", "DURATION", "[DESCRIPTION]")
+		p := NewParser(Group("outer", "test group", func(p *argparse.Command) Plugin {
+			return Plugin{
+				CMD:  p.NewCommand("log", "test positionals"),
+				Func: func(context.Context, Env) error { return nil },
+				Args: args,
+			}
+		}))
+		var out bytes.Buffer
+		err := p.Execute(context.Background(), strings.Fields(test.line), Env{Stdout: &out})
+		if code := Code(err); code != test.code {
+			t.Errorf("ERROR: %s exited with %d, expected %d (%v)", test.line, code, test.code, err)
+			continue
+		}
+		if got := strings.Join([]string{args.Get(0), args.Get(1), args.Get(2)}, ","); test.code == ExitOK && got != test.want {
+			t.Errorf("ERROR: %s gave %s, expected %s", test.line, got, test.want)
+		}
+	}
+	t.Logf("SUCCESS: parsed %d command lines", len(tests))
+}
+
+func TestMachineReadable(t *testing.T) {
+	var tests = map[string]bool{
+		"halp jira worklog":                 false,
+		"halp jira worklog -o table":        false,
+		"halp jira worklog -o json":         true,
+		"halp jira worklog --output=csv":    true,
+		"halp jira worklog -oyaml":          true,
+		"halp completion bash":              true,
+		"halp completion values --flag -p":  true,
+		"halp jira log NTC-1 1h completion": false,
+	}
+	for line, want := range tests {
+		if got := MachineReadable(strings.Fields(line)); got != want {
+			t.Errorf("ERROR: MachineReadable(%s) = %t, expected %t", line, got, want)
+		}
+	}
+	t.Logf("SUCCESS: checked %d command lines", len(tests))
+}
@@ -23,4 +23,5 @@ require (
 	golang.org/x/crypto v0.0.0-20201012173705-84dcc777aaee // indirect
 	golang.org/x/sys v0.0.0-20201015000850-e3ed0017c211 // indirect
 	gopkg.in/ini.v1 v1.41.0 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200605160147-a5ece683394c
 )
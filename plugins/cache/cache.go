@@ -0,0 +1,102 @@
+// Package cache holds the commands used to inspect and clear the on-disk
+// cache the other plugins keep API responses in.
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jokelyo/argparse"
+	"github.com/josh5276/halp/core"
+	"github.com/josh5276/halp/shared"
+	"github.com/josh5276/halp/shared/cache"
+)
+
+// Plugin function will return a argparse.Command type back to the parent parser
+// nolint:typecheck
+func Plugin(p *argparse.Command) core.Plugin {
+	return core.Group("cache", "Inspect or clear the local cache of JIRA/Tempo data.",
+		statsPlugin,
+		clearPlugin,
+	)(p)
+}
+
+func statsPlugin(p *argparse.Command) core.Plugin {
+	cmd := p.NewCommand("stats", "Show the number, age and size of the cached entries")
+	return core.Plugin{CMD: cmd, Func: statsFunc}
+}
+
+func clearPlugin(p *argparse.Command) core.Plugin {
+	cmd := p.NewCommand("clear", "Remove every cached entry")
+	return core.Plugin{CMD: cmd, Func: clearFunc}
+}
+
+// statsFunc function is executed from the halp caller
+func statsFunc(_ context.Context, env core.Env) error {
+	c, err := cache.FromSettings(env.Settings)
+	if err != nil {
+		return core.WithCode(core.ExitConfig, err)
+	}
+	stats, err := c.Stats()
+	if err != nil {
+		return err
+	}
+
+	r := shared.Report{
+		Title: fmt.Sprintf("Cache %s", c.Dir),
+		Columns: []shared.Column{
+			{Name: "BUCKET"},
+			{Name: "ENTRIES"},
+			{Name: "EXPIRED"},
+			{Name: "SIZE", Key: "bytes", Format: formatBytes},
+			{Name: "OLDEST", Format: func(v interface{}) string {
+				return v.(time.Time).Format("2006-01-02 15:04")
+			}},
+		},
+	}
+	total := cache.Stats{}
+	for _, s := range stats {
+		r.Rows = append(r.Rows, []interface{}{s.Bucket, s.Entries, s.Expired, s.Bytes, s.Oldest})
+		total.Entries += s.Entries
+		total.Expired += s.Expired
+		total.Bytes += s.Bytes
+	}
+	r.Footer = []interface{}{"Total", total.Entries, total.Expired, total.Bytes}
+	if !c.Enabled() {
+		r.Notes = []string{"The cache is disabled, set a ttl in the [cache] section to enable it"}
+	}
+	return shared.Render(env.Stdout, env.Output, r)
+}
+
+// clearFunc function is executed from the halp caller
+func clearFunc(_ context.Context, env core.Env) error {
+	c, err := cache.FromSettings(env.Settings)
+	if err != nil {
+		return core.WithCode(core.ExitConfig, err)
+	}
+	removed, err := c.Clear()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(env.Stdout, "Removed %d cached entries from %s\n", removed, c.Dir)
+	return nil
+}
+
+// formatBytes shows a size in the largest unit it fills.
+func formatBytes(v interface{}) string {
+	size, ok := v.(int64)
+	if !ok {
+		return fmt.Sprint(v)
+	}
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
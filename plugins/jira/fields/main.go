@@ -0,0 +1,115 @@
+package fields
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jokelyo/argparse"
+	"github.com/josh5276/halp/shared"
+	"github.com/josh5276/halp/shared/atlassian"
+
+	"github.com/josh5276/halp/core"
+)
+
+// maxAllowed is the width the allowed values are truncated to in tables.
+const maxAllowed = 60
+
+var (
+	// project and issueType narrow down the fields listed.
+	project   *string
+	issueType *string
+)
+
+// SubPlugin function will return a argparse.Command type back to the parent parser
+// nolint:typecheck
+func SubPlugin(p *argparse.Command) core.Plugin {
+	cmd := p.NewCommand("fields", "List the fields issues are created with, which are required and the values "+
+		"they allow. Set them on halp jira issue with --field NAME=VALUE.")
+	project = cmd.String("", "project", &argparse.Options{Help: "Only list the fields of this project key"})
+	issueType = cmd.String("", "type", &argparse.Options{Help: "Only list the fields of this issue type, e.g. Task"})
+	return core.Plugin{CMD: cmd, Func: pluginFunc}
+}
+
+// pluginFunc function is executed from the caller
+func pluginFunc(ctx context.Context, env core.Env) error {
+	atl, err := env.Atlassian()
+	if err != nil {
+		return core.WithCode(core.ExitConfig, err)
+	}
+	meta, err := atl.CreateMeta(ctx, *project, *issueType)
+	if err != nil {
+		return core.WithCode(core.ExitAPI, err)
+	}
+	if len(meta.Projects) == 0 {
+		return core.Errorf(core.ExitUsage, "no project found you can create issues in, check --project")
+	}
+	return shared.Render(env.Stdout, env.Output, report(meta))
+}
+
+// report lists the fields of every issue type, the required ones first.
+func report(meta atlassian.CreateMeta) shared.Report {
+	r := shared.Report{
+		Title: "Issue fields",
+		Columns: []shared.Column{
+			{Name: "PROJECT"},
+			{Name: "ISSUE TYPE"},
+			{Name: "FIELD"},
+			{Name: "ID"},
+			{Name: "TYPE"},
+			{Name: "REQUIRED", Format: formatRequired},
+			{Name: "ALLOWED VALUES", Format: formatAllowed},
+		},
+	}
+	for _, p := range meta.Projects {
+		for _, t := range p.IssueTypes {
+			ids := make([]string, 0, len(t.Fields))
+			for id := range t.Fields {
+				ids = append(ids, id)
+			}
+			sort.Slice(ids, func(i, j int) bool {
+				a, b := t.Fields[ids[i]], t.Fields[ids[j]]
+				if a.Required != b.Required {
+					return a.Required
+				}
+				return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+			})
+			for _, id := range ids {
+				f := t.Fields[id]
+				allowed := make([]string, 0, len(f.AllowedValues))
+				for _, v := range f.AllowedValues {
+					allowed = append(allowed, v.Label())
+				}
+				r.Rows = append(r.Rows, []interface{}{p.Key, t.Name, f.Name, id, schemaType(f), f.Required, allowed})
+			}
+		}
+	}
+	if len(r.Rows) == 0 {
+		r.Notes = append(r.Notes, "No issue types found, check --type")
+	}
+	return r
+}
+
+// schemaType describes the type of a field, e.g. option or array of option.
+func schemaType(f atlassian.FieldMeta) string {
+	if f.Schema.Type == "array" {
+		return fmt.Sprintf("array of %s", f.Schema.Items)
+	}
+	return f.Schema.Type
+}
+
+func formatRequired(v interface{}) string {
+	if required, ok := v.(bool); ok && required {
+		return "yes"
+	}
+	return ""
+}
+
+func formatAllowed(v interface{}) string {
+	allowed, ok := v.([]string)
+	if !ok {
+		return fmt.Sprint(v)
+	}
+	return shared.Truncate(strings.Join(allowed, ", "), maxAllowed)
+}
@@ -0,0 +1,57 @@
+package fields
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/josh5276/halp/core"
+	"github.com/josh5276/halp/shared/atlassian/fake"
+)
+
+// testRun runs `halp jira fields` against the fake server and returns what it printed.
+func testRun(t *testing.T, srv *fake.Server, args ...string) (string, error) {
+	return fake.Run(t, srv, SubPlugin, "jira fields", core.Env{}, args...)
+}
+
+func TestPlugin_fields(t *testing.T) {
+	srv := fake.Start(t, fake.SharedFixture())
+	defer srv.Close()
+
+	out, err := testRun(t, srv, "--project", "ntc", "--type", "change", "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Rows []map[string]interface{}
+	}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("ERROR: decoding %s:%s", out, err)
+	}
+	// The required fields come first, Account before Customer.
+	if len(doc.Rows) != 12 || doc.Rows[0]["field"] != "Account" || doc.Rows[1]["id"] != "customfield_10010" ||
+		len(doc.Rows[1]["allowed_values"].([]interface{})) != 2 || doc.Rows[1]["required"] != true {
+		t.Fatalf("ERROR: unexpected fields %s", out)
+	}
+
+	out, err = testRun(t, srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Sub-task", "array of option", "ACME, Globex"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("ERROR: fields are missing %q:\n%s", want, out)
+		}
+	}
+
+	// CSV has no lists, the allowed values are written as they are in the table.
+	out, err = testRun(t, srv, "--project", "ntc", "--type", "change", "-o", "csv")
+	if err != nil || !strings.Contains(out, `,"ACME, Globex"`) || strings.Contains(out, "[") {
+		t.Fatalf("ERROR: expected the allowed values to be joined, %v:\n%s", err, out)
+	}
+
+	if _, err := testRun(t, srv, "--project", "nope"); core.Code(err) != core.ExitUsage {
+		t.Fatalf("ERROR: expected an unknown project to be a usage error, got %v", err)
+	}
+	t.Logf("SUCCESS: listed %d fields", len(doc.Rows))
+}
@@ -0,0 +1,50 @@
+package issue
+
+import (
+	"context"
+	"strings"
+
+	"github.com/josh5276/halp/core"
+	"github.com/josh5276/halp/shared/atlassian"
+)
+
+// customFields converts the --field name=value pairs to the JSON JIRA expects for
+// each field, looking the names up in the create metadata of the project and issue type.
+func customFields(ctx context.Context, atl *atlassian.Client, fields atlassian.IssueField, pairs []string) (map[string]interface{}, error) {
+	meta, err := atl.CreateMeta(ctx, fields.Project.Key, fields.IssueType.Name)
+	if err != nil {
+		return nil, core.WithCode(core.ExitAPI, err)
+	}
+	issueType, err := meta.IssueType(fields.Project.Key, fields.IssueType.Name)
+	if err != nil {
+		return nil, core.WithCode(core.ExitUsage, err)
+	}
+
+	custom := make(map[string]interface{}, len(pairs))
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, core.Errorf(core.ExitUsage, "--field %q is not a name=value pair", pair)
+		}
+		id, field, err := issueType.Field(strings.TrimSpace(kv[0]))
+		if err != nil {
+			return nil, core.Errorf(core.ExitUsage, "%s, see halp jira fields --project %s --type %q",
+				err, fields.Project.Key, fields.IssueType.Name)
+		}
+		value := kv[1]
+		if isUser(field) && strings.EqualFold(strings.TrimSpace(value), "me") {
+			if value, err = atl.AccountID(ctx); err != nil {
+				return nil, core.WithCode(core.ExitAPI, err)
+			}
+		}
+		if custom[id], err = field.Value(value); err != nil {
+			return nil, core.WithCode(core.ExitUsage, err)
+		}
+	}
+	return custom, nil
+}
+
+// isUser reports whether the field takes a user, where me stands for yourself.
+func isUser(f atlassian.FieldMeta) bool {
+	return f.Schema.Type == "user" || (f.Schema.Type == "array" && f.Schema.Items == "user")
+}
@@ -1,11 +1,16 @@
 package issue
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"os/exec"
 	"strings"
 
 	"github.com/jokelyo/argparse"
-	"github.com/josh5276/halp/core/keyring"
+	"github.com/josh5276/halp/shared"
 	"github.com/josh5276/halp/shared/atlassian"
 	"github.com/tcnksm/go-input"
 
@@ -13,78 +18,321 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	// defaultType is the issue type created unless --type is given.
+	defaultType = "Task"
+	// subTaskType is the issue type created under a --parent unless --type is given.
+	subTaskType = "Sub-task"
+	// editorScissors is appended to the description opened with --editor, like in a
+	// git commit message the line and everything below it are cut from the description.
+	editorScissors = "# ------------------------ >8 ------------------------"
+	// editorComment follows the scissors, explaining them.
+	editorComment = "# Write the description of the issue above, this line and everything below it are ignored."
+)
+
 var (
-	ui      = &input.UI{Writer: os.Stdout, Reader: os.Stdin}
 	options = &input.Options{Required: false, Mask: false, HideOrder: true}
+
+	// flags holds the issue fields given on the command line.
+	flags issueArgs
+
+	// isTerminal decides whether missing fields are prompted for, the tests
+	// answer the prompts from a string instead of a terminal.
+	isTerminal = shared.IsTerminal
 )
 
+// issueArgs are the flags setting the fields of the new issue. Anything left
+// out is prompted for when stdin is a terminal.
+type issueArgs struct {
+	template    *string
+	vars        *[]string
+	project     *string
+	summary     *string
+	description *string
+	editor      *bool
+	issueType   *string
+	priority    *string
+	labels      *string
+	assignee    *string
+	parent      *string
+	components  *string
+	fields      *[]string
+}
+
 // SubPlugin function will return a argparse.Command type back to the parent parser
 // nolint:typecheck
 func SubPlugin(p *argparse.Command) core.Plugin {
 	// Create a command and argument for the ip audit
-	cmd := p.NewCommand("issue", "Create a JIRA issue.")
+	cmd := p.NewCommand("issue", "Create a JIRA issue. Fields that are not given are prompted for on a terminal.")
+	flags = issueArgs{
+		template: cmd.String("", "template", &argparse.Options{
+			Help: "Name of an issue template from the settings, the other flags override its fields",
+		}),
+		vars: cmd.StringList("", "var", &argparse.Options{
+			Help: "name=value filling a placeholder of the --template, repeat it for each one",
+		}),
+		project: cmd.String("", "project", &argparse.Options{Help: "Key of the project to create the issue in"}),
+		summary: cmd.String("", "summary", &argparse.Options{Help: "Summary of the issue"}),
+		description: cmd.String("", "description", &argparse.Options{
+			Help: "Description of the issue, - reads it from stdin",
+		}),
+		editor: cmd.Flag("", "editor", &argparse.Options{
+			Help: "Write the description in $EDITOR, starting from --description when given",
+		}),
+		issueType: cmd.String("", "type", &argparse.Options{
+			Help: fmt.Sprintf("Issue type, defaults to %s, or %s with --parent", defaultType, subTaskType),
+		}),
+		priority: cmd.String("", "priority", &argparse.Options{Help: "Priority name, e.g. High"}),
+		labels:   cmd.String("", "labels", &argparse.Options{Help: "Comma separated labels"}),
+		assignee: cmd.String("", "assignee", &argparse.Options{
+			Help: "Account ID of the assignee, or me to assign it to yourself",
+		}),
+		parent:     cmd.String("", "parent", &argparse.Options{Help: "Key of the parent issue, to create a sub-task"}),
+		components: cmd.String("", "components", &argparse.Options{Help: "Comma separated component names"}),
+		fields: cmd.StringList("", "field", &argparse.Options{
+			Help: "name=value setting any other field by its name or ID, repeat it for each one. " +
+				"See halp jira fields for the fields and values allowed",
+		}),
+	}
 	return core.Plugin{CMD: cmd, Func: pluginFunc}
 }
 
 // pluginFunc function is executed from the caller
-func pluginFunc(cfg keyring.Settings) {
-	tempoToken, err := cfg.TempoToken()
-	if err != nil {
-		logrus.Fatalf("cfg.Tempo:%s", err)
-	}
-
-	jiraToken, err := cfg.JIRAToken()
+func pluginFunc(ctx context.Context, env core.Env) error {
+	atl, err := env.Atlassian()
 	if err != nil {
-		logrus.Fatalf("cfg.Tempo:%s", err)
+		return core.WithCode(core.ExitConfig, err)
 	}
 
 	var (
-		projectID   string
-		summary     string
-		description string
+		in          = &stdin{Reader: env.Stdin}
+		ui          = &input.UI{Writer: env.Stdout, Reader: in}
+		interactive = isTerminal(env.Stdin) && *flags.description != "-"
+		// values are the fields given on the command line, keyed like templateFields.
+		values = map[string]string{
+			"project":     *flags.project,
+			"summary":     *flags.summary,
+			"description": *flags.description,
+			"type":        *flags.issueType,
+			"priority":    *flags.priority,
+			"labels":      *flags.labels,
+			"assignee":    *flags.assignee,
+			"parent":      *flags.parent,
+			"components":  *flags.components,
+		}
 	)
+	if values["description"] == "-" {
+		b, err := ioutil.ReadAll(env.Stdin)
+		if err != nil {
+			return core.Errorf(core.ExitUsage, "JIRA:Issue:Description.ReadAll:%w", err)
+		}
+		values["description"] = string(b)
+	}
+	if *flags.template != "" {
+		if err := applyTemplate(env, ui, in, interactive, values); err != nil {
+			return err
+		}
+	}
+	for key, value := range values {
+		values[key] = strings.TrimSpace(value)
+	}
+	if *flags.editor {
+		if values["description"], err = editor(ctx, env, values["description"]); err != nil {
+			return core.WithCode(core.ExitUsage, err)
+		}
+	}
 
-	for {
-		if projectID, err = ui.Ask("Associated Project ID", options); err != nil {
-			logrus.Fatalf("JIRA:Issue:ProjectID.Ask:%s", err)
+	if !interactive {
+		for _, key := range []string{"project", "summary"} {
+			if values[key] == "" {
+				return core.Errorf(core.ExitUsage, "--%s is required when stdin is not a terminal", key)
+			}
 		}
-		if strings.TrimSpace(projectID) != "" {
-			break
+	}
+	if values["project"] == "" {
+		if values["project"], err = ask(ui, in, "ProjectID", "Associated Project ID", true); err != nil {
+			return err
+		}
+	}
+	if values["summary"] == "" {
+		if values["summary"], err = ask(ui, in, "Summary", "Summary", true); err != nil {
+			return err
+		}
+	}
+	if interactive && values["description"] == "" && !*flags.editor {
+		if values["description"], err = ask(ui, in, "Description", "Description", false); err != nil {
+			return err
+		}
+	}
+
+	fields := atlassian.IssueField{
+		Summary:     values["summary"],
+		Description: values["description"],
+		Labels:      split(values["labels"]),
+	}
+	fields.Project.Key = strings.ToUpper(values["project"])
+	fields.IssueType.Name = values["type"]
+	if fields.IssueType.Name == "" {
+		fields.IssueType.Name = defaultType
+	}
+	if values["parent"] != "" {
+		fields.Parent = &atlassian.IssueKey{Key: strings.ToUpper(values["parent"])}
+		if values["type"] == "" {
+			fields.IssueType.Name = subTaskType
+		}
+	}
+	if values["priority"] != "" {
+		fields.Priority = &atlassian.IssueName{Name: values["priority"]}
+	}
+	for _, name := range split(values["components"]) {
+		fields.Components = append(fields.Components, atlassian.IssueName{Name: name})
+	}
+	switch assignee := values["assignee"]; {
+	case strings.EqualFold(assignee, "me"):
+		accountID, err := atl.AccountID(ctx)
+		if err != nil {
+			return core.WithCode(core.ExitAPI, err)
+		}
+		fields.Assignee = &atlassian.IssueUser{AccountID: accountID}
+	case assignee != "":
+		fields.Assignee = &atlassian.IssueUser{AccountID: assignee}
+	}
+
+	if len(*flags.fields) > 0 {
+		if fields.Custom, err = customFields(ctx, atl, fields, *flags.fields); err != nil {
+			return err
+		}
+	}
+
+	response, err := atl.NewIssue(ctx, atlassian.IssueRequest{Fields: fields})
+	if err != nil {
+		return core.WithCode(core.ExitAPI, err)
+	}
+	logrus.Infof("Successfully created issue %s.", response.Key)
+	return nil
+}
+
+// applyTemplate fills the fields missing from values with the --template,
+// taking its placeholders from --var or prompting for them.
+func applyTemplate(env core.Env, ui *input.UI, in *stdin, interactive bool, values map[string]string) error {
+	t, err := loadTemplate(env.Settings, *flags.template)
+	if err != nil {
+		return core.WithCode(core.ExitConfig, err)
+	}
+	vars, err := parseVars(*flags.vars)
+	if err != nil {
+		return core.WithCode(core.ExitUsage, err)
+	}
+
+	given := func(field string) bool { return values[field] != "" }
+	data := make(map[string]string)
+	for _, name := range t.placeholders(given) {
+		value, ok := vars[strings.ToLower(name)]
+		if !ok && !interactive {
+			return core.Errorf(core.ExitUsage, "--template %s needs --var %s=VALUE when stdin is not a terminal",
+				t.name, name)
+		} else if !ok {
+			if value, err = ask(ui, in, name, name, true); err != nil {
+				return err
+			}
 		}
-		logrus.Error("Project ID is required.")
+		data[name] = value
+	}
+	filled, err := t.execute(data, given)
+	if err != nil {
+		return core.WithCode(core.ExitConfig, err)
+	}
+	for field, value := range filled {
+		values[field] = value
 	}
+	return nil
+}
 
+// ask prompts for a field, asking again until it is answered when it is required.
+func ask(ui *input.UI, in *stdin, name, query string, required bool) (string, error) {
 	for {
-		if summary, err = ui.Ask("Summary", options); err != nil {
-			logrus.Fatalf("JIRA:Issue:Summary.Ask:%s", err)
+		answer, err := ui.Ask(query, options)
+		if err != nil {
+			return "", core.Errorf(core.ExitUsage, "JIRA:Issue:%s.Ask:%w", name, err)
+		}
+		if answer = strings.TrimSpace(answer); answer != "" || !required {
+			return answer, nil
 		}
+		if in.eof {
+			return "", core.Errorf(core.ExitUsage, "JIRA:Issue:%s.Ask:%w", name, io.ErrUnexpectedEOF)
+		}
+		logrus.Errorf("%s is required.", query)
+	}
+}
+
+// editor opens the description in $EDITOR, vi when it is not set, and returns
+// what was saved without the comment lines.
+func editor(ctx context.Context, env core.Env, description string) (string, error) {
+	f, err := ioutil.TempFile("", "halp-issue-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("JIRA:Issue:editor.TempFile:%s", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = fmt.Fprintf(f, "%s\n\n%s\n%s\n", description, editorScissors, editorComment)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("JIRA:Issue:editor.Write:%s", err)
+	}
+
+	command := strings.Fields(os.Getenv("EDITOR"))
+	if len(command) == 0 {
+		command = []string{"vi"}
+	}
+	cmd := exec.CommandContext(ctx, command[0], append(command[1:], f.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = env.Stdin, env.Stdout, env.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("JIRA:Issue:editor.Run:%s:%s", command[0], err)
+	}
 
-		if strings.TrimSpace(summary) != "" {
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		return "", fmt.Errorf("JIRA:Issue:editor.ReadFile:%s", err)
+	}
+	// Markdown headings and shell comments start with # too, so only our own lines
+	// are dropped, even when the scissors were removed.
+	lines := make([]string, 0)
+	for _, line := range strings.Split(string(b), "\n") {
+		trimmed := strings.TrimRight(line, "\r")
+		if trimmed == editorScissors {
 			break
 		}
-		logrus.Error("Summary is required.")
+		if trimmed != editorComment {
+			lines = append(lines, line)
+		}
 	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
 
-	if description, err = ui.Ask("Description", options); err != nil {
-		logrus.Fatalf("JIRA:Issue:Description.Ask:%s", err)
+// split splits a comma separated flag into its trimmed, non-empty values.
+func split(s string) []string {
+	values := make([]string, 0)
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
 	}
+	return values
+}
 
-	atl := atlassian.New(cfg.JIRAUser, jiraToken.Password, tempoToken.Password, cfg.JIRAInstance)
+// stdin wraps the input the prompts read from to notice when it runs out.
+// go-input answers an empty string at the end of the input, which would
+// make the required prompts ask again forever when stdin is not a terminal.
+type stdin struct {
+	io.Reader
+	eof bool
+}
 
-	response, err := atl.NewIssue(atlassian.IssueRequest{
-		Fields: atlassian.IssueField{
-			Project: struct {
-				Key string `json:"key"`
-			}{strings.ToUpper(projectID)},
-			Summary:     summary,
-			Description: description,
-			IssueType: struct {
-				Name string `json:"name"`
-			}{"Task"},
-		},
-	})
-	if err != nil {
-		logrus.Fatal(err)
+func (s *stdin) Read(p []byte) (int, error) {
+	n, err := s.Reader.Read(p)
+	if err == io.EOF {
+		s.eof = true
 	}
-	logrus.Infof("Successfully created issue %s.", response.Key)
+	return n, err
 }
@@ -0,0 +1,174 @@
+package issue
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/josh5276/halp/core"
+	"github.com/josh5276/halp/shared"
+	"github.com/josh5276/halp/shared/atlassian/fake"
+)
+
+// testRun runs `halp jira issue` with the settings in cfg against the fake server,
+// answering the prompts with the lines of stdin as if it were a terminal when tty is set.
+func testRun(t *testing.T, srv *fake.Server, cfg string, tty bool, stdin string, args ...string) (string, error) {
+	isTerminal = func(io.Reader) bool { return tty }
+	defer func() { isTerminal = shared.IsTerminal }()
+
+	return fake.Run(t, srv, SubPlugin, "jira issue", core.Env{
+		Settings: fake.Settings(t, cfg),
+		Stdin:    strings.NewReader(stdin),
+	}, args...)
+}
+
+func TestPlugin_issue(t *testing.T) {
+	srv := fake.NewServer(fake.Fixture{})
+	defer srv.Close()
+
+	if _, err := testRun(t, srv, "", true, "ntc\nAutomate the backups\nNightly config backups\n"); err != nil {
+		t.Fatal(err)
+	}
+	if len(srv.Created) != 1 {
+		t.Fatalf("ERROR: expected one issue to be created, got %d", len(srv.Created))
+	}
+	fields := srv.Created[0].Fields
+	if fields.Project.Key != "NTC" || fields.Summary != "Automate the backups" ||
+		fields.Description != "Nightly config backups" || fields.IssueType.Name != "Task" {
+		t.Fatalf("ERROR: unexpected issue %+v", fields)
+	}
+	t.Logf("SUCCESS: created %+v", fields)
+}
+
+func TestPlugin_issueErrors(t *testing.T) {
+	srv := fake.NewServer(fake.Fixture{})
+	defer srv.Close()
+
+	// The prompts run out of input before the summary is given.
+	if _, err := testRun(t, srv, "", true, "ntc\n"); core.Code(err) != core.ExitUsage {
+		t.Errorf("ERROR: expected a usage error without a summary, got %v", err)
+	}
+
+	srv.Script(fake.Response{
+		Method: http.MethodPost,
+		Path:   "/rest/api/2/issue/",
+		Status: http.StatusBadRequest,
+		Body:   `{"errorMessages": [], "errors": {"project": "valid project is required"}}`,
+	})
+	_, err := testRun(t, srv, "", true, "nope\nAutomate the backups\n\n")
+	if core.Code(err) != core.ExitAPI || !strings.Contains(err.Error(), "project: valid project is required") {
+		t.Fatalf("ERROR: expected the JIRA field error, got %v", err)
+	}
+	if len(srv.Created) != 0 {
+		t.Fatalf("ERROR: expected no issue to be created")
+	}
+	t.Logf("SUCCESS: %s", err)
+}
+
+func TestPlugin_issueFlags(t *testing.T) {
+	srv := fake.Start(t, fake.SharedFixture())
+	defer srv.Close()
+
+	// Nothing is prompted for when stdin is not a terminal, the description comes from it.
+	_, err := testRun(t, srv, "", false, "Nightly config backups\n", "--project", "ntc", "--summary", "Automate the backups",
+		"--description", "-", "--parent", "NTC-1", "--priority", "High", "--labels", "backup, automation",
+		"--assignee", "me", "--components", "Network")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := srv.Created[0].Fields
+	if fields.Description != "Nightly config backups" || fields.IssueType.Name != subTaskType ||
+		fields.Parent.Key != "NTC-1" || fields.Priority.Name != "High" || len(fields.Labels) != 2 ||
+		fields.Assignee.AccountID != "abc123" || fields.Components[0].Name != "Network" {
+		t.Fatalf("ERROR: unexpected issue %+v", fields)
+	}
+
+	if _, err := testRun(t, srv, "", false, "", "--project", "ntc"); core.Code(err) != core.ExitUsage ||
+		!strings.Contains(err.Error(), "--summary is required") {
+		t.Fatalf("ERROR: expected the missing summary to be a usage error, got %v", err)
+	}
+
+	// Only the missing summary is prompted for on a terminal.
+	if _, err := testRun(t, srv, "", true, "Prompted summary\n", "--project", "ntc", "--description", "Given"); err != nil {
+		t.Fatal(err)
+	}
+	if fields := srv.Created[1].Fields; fields.Summary != "Prompted summary" || fields.Description != "Given" ||
+		fields.IssueType.Name != defaultType {
+		t.Fatalf("ERROR: unexpected issue %+v", fields)
+	}
+	t.Logf("SUCCESS: created %d issues", len(srv.Created))
+}
+
+func TestPlugin_issueEditor(t *testing.T) {
+	srv := fake.NewServer(fake.Fixture{})
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "halp-editor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// The editor adds a line under the description it was given and another under
+	// the scissors, which is cut.
+	script := filepath.Join(dir, "editor.sh")
+	if err := ioutil.WriteFile(script, []byte("#!/bin/sh\nsed -i -e '1a Written in the editor' -e '$a Below the scissors' \"$1\"\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("EDITOR", os.Getenv("EDITOR"))
+	os.Setenv("EDITOR", script)
+
+	tests := []struct {
+		description string
+		want        string
+	}{
+		{description: "Started here", want: "Started here\nWritten in the editor"},
+		// Lines of the description starting with # are kept.
+		{description: "# step one", want: "# step one\nWritten in the editor"},
+	}
+	for i, tt := range tests {
+		_, err = testRun(t, srv, "", false, "", "--project", "ntc", "--summary", "Edited", "--description", tt.description, "--editor")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if description := srv.Created[i].Fields.Description; description != tt.want {
+			t.Fatalf("ERROR: unexpected description %q, expected %q", description, tt.want)
+		}
+		t.Logf("SUCCESS: %q", srv.Created[i].Fields.Description)
+	}
+}
+
+func TestPlugin_issueCustomFields(t *testing.T) {
+	srv := fake.Start(t, fake.SharedFixture())
+	defer srv.Close()
+
+	args := []string{"--project", "ntc", "--type", "change", "--summary", "Core upgrade",
+		"--field", "customer=acme", "--field", "Reviewer=me", "--field", "Sites=DFW3,iad2", "--field", "duedate=2020-11-02"}
+	// The required Account is missing.
+	_, err := testRun(t, srv, "", false, "", args...)
+	if core.Code(err) != core.ExitAPI || !strings.Contains(err.Error(), "Account is required") {
+		t.Fatalf("ERROR: expected the missing Account to be reported, got %v", err)
+	}
+
+	if _, err := testRun(t, srv, "", false, "", append(args, "--field", "customfield_10011=ACME-001")...); err != nil {
+		t.Fatal(err)
+	}
+	custom := srv.Created[0].Fields.Custom
+	if custom["customfield_10010"].(map[string]interface{})["value"] != "ACME" ||
+		custom["customfield_10013"].(map[string]interface{})["accountId"] != "abc123" ||
+		custom["customfield_10011"] != "ACME-001" || len(custom["customfield_10014"].([]interface{})) != 2 {
+		t.Fatalf("ERROR: unexpected custom fields %v", custom)
+	}
+
+	for _, field := range []string{"Story Points=three", "Customer=Initech", "Owner=me", "duedate=next week"} {
+		_, err := testRun(t, srv, "", false, "", "--project", "ntc", "--type", "change", "--summary", "Core upgrade",
+			"--field", field)
+		if core.Code(err) != core.ExitUsage {
+			t.Errorf("ERROR: expected --field %s to be a usage error, got %v", field, err)
+		}
+	}
+	t.Logf("SUCCESS: created an issue with %d custom fields", len(custom))
+}
@@ -0,0 +1,162 @@
+package issue
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/josh5276/halp/core/keyring"
+	"github.com/josh5276/halp/shared"
+)
+
+// templatePrefix starts the settings.ini sections holding the issue templates
+// used with --template NAME. Every field is a Go text/template whose
+// placeholders are filled with --var or prompted for, e.g.
+//
+//	[issue.template.change-request]
+//	project     = NTC
+//	type        = Change
+//	labels      = change, {{.Site}}
+//	summary     = Change request: {{.Change}} at {{.Site}}
+//	description = """Change: {{.Change}}
+//	Rollback: {{.Rollback}}"""
+const templatePrefix = "issue.template."
+
+// templateFields are the fields a template can set, named after their flags.
+var templateFields = []string{"project", "summary", "description", "type", "priority", "labels", "assignee",
+	"parent", "components"}
+
+// issueTemplate is a named set of default fields for a recurring kind of issue.
+type issueTemplate struct {
+	name   string
+	fields map[string]*template.Template
+}
+
+// loadTemplate reads and parses the template called name from the settings.
+func loadTemplate(cfg keyring.Settings, name string) (*issueTemplate, error) {
+	section := templatePrefix + strings.ToLower(name)
+	keys := cfg.Keys(section)
+	if len(keys) == 0 {
+		available := cfg.Sections(templatePrefix)
+		if len(available) == 0 {
+			return nil, fmt.Errorf("JIRA:Issue:loadTemplate:no template %q, add a [%s%s] section to the settings",
+				name, templatePrefix, name)
+		}
+		return nil, fmt.Errorf("JIRA:Issue:loadTemplate:no template %q, use one of %s",
+			name, strings.Join(available, ", "))
+	}
+
+	funcs := template.FuncMap{"today": func() string { return time.Now().Format(shared.DateLayout) }}
+	t := &issueTemplate{name: name, fields: make(map[string]*template.Template, len(keys))}
+	for _, key := range keys {
+		if !known(key) {
+			return nil, fmt.Errorf("JIRA:Issue:loadTemplate:[%s] has an unknown field %q, use %s",
+				section, key, strings.Join(templateFields, ", "))
+		}
+		field, err := template.New(key).Funcs(funcs).Option("missingkey=error").Parse(cfg.Value(section, key))
+		if err != nil {
+			return nil, fmt.Errorf("JIRA:Issue:loadTemplate:[%s] %s:%s", section, key, err)
+		}
+		t.fields[key] = field
+	}
+	return t, nil
+}
+
+// placeholders returns the names of the placeholders in the fields, in the
+// order they appear, leaving out the fields set some other way.
+func (t *issueTemplate) placeholders(skip func(field string) bool) []string {
+	var (
+		names = make([]string, 0)
+		seen  = make(map[string]bool)
+	)
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				for _, arg := range cmd.Args {
+					walk(arg)
+				}
+			}
+		case *parse.FieldNode:
+			if name := n.Ident[0]; !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		}
+	}
+	for _, field := range templateFields {
+		if t.fields[field] != nil && !skip(field) {
+			walk(t.fields[field].Tree.Root)
+		}
+	}
+	return names
+}
+
+// execute fills the placeholders of the fields with vars, leaving out the
+// fields set some other way.
+func (t *issueTemplate) execute(vars map[string]string, skip func(field string) bool) (map[string]string, error) {
+	values := make(map[string]string, len(t.fields))
+	for key, field := range t.fields {
+		if skip(key) {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := field.Execute(&buf, vars); err != nil {
+			return nil, fmt.Errorf("JIRA:Issue:template %s:%s", t.name, err)
+		}
+		values[key] = strings.TrimSpace(buf.String())
+	}
+	return values, nil
+}
+
+// known reports whether a template field is one of templateFields.
+func known(field string) bool {
+	for _, f := range templateFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// parseVars parses the name=value pairs given with --var, keyed by the lower
+// case name so placeholders match them whatever their case.
+func parseVars(pairs []string) (map[string]string, error) {
+	vars := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("--var %q is not a name=value pair", pair)
+		}
+		vars[strings.ToLower(strings.TrimSpace(kv[0]))] = kv[1]
+	}
+	return vars, nil
+}
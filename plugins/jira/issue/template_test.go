@@ -0,0 +1,57 @@
+package issue
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/josh5276/halp/core"
+	"github.com/josh5276/halp/shared/atlassian/fake"
+)
+
+const testTemplates = `
+[issue.template.change-request]
+project     = NTC
+type        = Change
+labels      = change, {{.Site}}
+summary     = Change request: {{.Change}} at {{.Site}}
+description = """Change: {{.Change}}
+{{if .Rollback}}Rollback: {{.Rollback}}{{end}}"""
+
+[issue.template.onboarding]
+project = OPS
+summary = Onboard {{.Name}}
+`
+
+func TestPlugin_issueTemplate(t *testing.T) {
+	srv := fake.NewServer(fake.Fixture{})
+	defer srv.Close()
+
+	_, err := testRun(t, srv, testTemplates, false, "", "--template", "change-request",
+		"--var", "site=DFW3", "--var", "Change=Core upgrade", "--var", "Rollback=Reload the old image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := srv.Created[0].Fields
+	if fields.Project.Key != "NTC" || fields.IssueType.Name != "Change" || fields.Summary != "Change request: Core upgrade at DFW3" ||
+		fields.Description != "Change: Core upgrade\nRollback: Reload the old image" || strings.Join(fields.Labels, ",") != "change,DFW3" {
+		t.Fatalf("ERROR: unexpected issue %+v", fields)
+	}
+
+	// Flags override the template, the placeholders they replace are not asked for.
+	if _, err := testRun(t, srv, testTemplates, true, "Jane\n", "--template", "onboarding", "--project", "ntc"); err != nil {
+		t.Fatal(err)
+	}
+	if fields := srv.Created[1].Fields; fields.Project.Key != "NTC" || fields.Summary != "Onboard Jane" {
+		t.Fatalf("ERROR: unexpected issue %+v", fields)
+	}
+
+	if _, err := testRun(t, srv, testTemplates, false, "", "--template", "change-request", "--var", "site=DFW3"); core.Code(err) != core.ExitUsage ||
+		!strings.Contains(err.Error(), "--var Change=VALUE") {
+		t.Fatalf("ERROR: expected the missing placeholder to be a usage error, got %v", err)
+	}
+	if _, err := testRun(t, srv, testTemplates, false, "", "--template", "incident"); core.Code(err) != core.ExitConfig ||
+		!strings.Contains(err.Error(), "change-request, onboarding") {
+		t.Fatalf("ERROR: expected the unknown template to list the others, got %v", err)
+	}
+	t.Logf("SUCCESS: created %d issues from templates", len(srv.Created))
+}
@@ -1,30 +1,32 @@
 package jira
 
 import (
+	"github.com/jokelyo/argparse"
 	"github.com/josh5276/halp/core"
-	"github.com/josh5276/halp/core/keyring"
+	"github.com/josh5276/halp/plugins/jira/fields"
 	"github.com/josh5276/halp/plugins/jira/issue"
+	"github.com/josh5276/halp/plugins/jira/logtime"
+	"github.com/josh5276/halp/plugins/jira/search"
+	"github.com/josh5276/halp/plugins/jira/timer"
+	"github.com/josh5276/halp/plugins/jira/view"
 	"github.com/josh5276/halp/plugins/jira/worklog"
+	"github.com/josh5276/halp/shared/atlassian"
 )
 
-var subPlugins = make([]core.Plugin, 0)
-
 // Plugin function will return a argparse.Command type back to the parent parser
 // nolint:typecheck
-func Plugin(p *core.Parser) core.Plugin {
-	cmd := p.NewCommand("jira", "Manage JIRA/Tempo operations.")
-	subPlugins = append(
-		subPlugins,
-		worklog.SubPlugin(cmd),
-		issue.SubPlugin(cmd),
-	)
-	return core.Plugin{CMD: cmd, Func: pluginFunc}
-}
-
-func pluginFunc(cfg keyring.Settings) {
-	for _, p := range subPlugins {
-		if p.CMD.Happened() {
-			p.Func(cfg)
-		}
-	}
+func Plugin(p *argparse.Command) core.Plugin {
+	// --project of worklog, issue and fields completes the projects of the cached issues.
+	core.CompleteFlag("project", func(env core.Env) ([]string, error) {
+		return atlassian.CachedProjects(env.Settings)
+	})
+	return core.Group("jira", "Manage JIRA/Tempo operations.",
+		worklog.SubPlugin,
+		issue.SubPlugin,
+		logtime.SubPlugin,
+		timer.SubPlugin,
+		fields.SubPlugin,
+		view.SubPlugin,
+		search.SubPlugin,
+	)(p)
 }
@@ -0,0 +1,128 @@
+package logtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jokelyo/argparse"
+	"github.com/josh5276/halp/shared"
+	"github.com/josh5276/halp/shared/atlassian"
+	"github.com/sirupsen/logrus"
+
+	"github.com/josh5276/halp/core"
+)
+
+var (
+	// args holds the issue key, the time spent and the optional description.
+	args *core.Args
+	// date and start say when the work was done.
+	date  *string
+	start *string
+	// yes skips the confirmation.
+	yes *bool
+)
+
+// SubPlugin function will return a argparse.Command type back to the parent parser
+// nolint:typecheck
+func SubPlugin(p *argparse.Command) core.Plugin {
+	args = core.Positional("ISSUE", "DURATION", "[DESCRIPTION]")
+	cmd := p.NewCommand("log", fmt.Sprintf("Log time to a JIRA issue in Tempo: log %s, "+
+		"e.g. log NTC-1 1h30m \"Design review\".", args.Usage()))
+	date = cmd.String("", "date", &argparse.Options{
+		Help: fmt.Sprintf("Day the work was done, %s. Defaults to today", shared.DateLayout),
+	})
+	start = cmd.String("", "start", &argparse.Options{Help: "Time the work started, HH:MM"})
+	yes = cmd.Flag("y", "yes", &argparse.Options{Help: "Log the time without asking for confirmation"})
+	return core.Plugin{CMD: cmd, Func: pluginFunc, Args: args}
+}
+
+// pluginFunc function is executed from the caller
+func pluginFunc(ctx context.Context, env core.Env) error {
+	loc, err := env.Settings.Location()
+	if err != nil {
+		return core.WithCode(core.ExitConfig, err)
+	}
+	req, err := newRequest(time.Now().In(loc))
+	if err != nil {
+		return core.WithCode(core.ExitUsage, err)
+	}
+
+	atl, err := env.Atlassian()
+	if err != nil {
+		return core.WithCode(core.ExitConfig, err)
+	}
+	issue, err := atl.JiraIssue(ctx, req.IssueKey)
+	if err != nil {
+		return core.WithCode(core.ExitAPI, err)
+	}
+	if req.AuthorAccountID, err = atl.AccountID(ctx); err != nil {
+		return core.WithCode(core.ExitAPI, err)
+	}
+
+	if !*yes {
+		fmt.Fprintf(env.Stdout, "Issue:       %s %s\n", issue.Key, issue.Fields.Summary)
+		fmt.Fprintf(env.Stdout, "Time spent:  %s\n", shared.FormatMinutes(req.TimeSpentSeconds/60))
+		when := req.StartDate
+		if req.StartTime != "" {
+			when += " " + strings.TrimSuffix(req.StartTime, ":00")
+		}
+		fmt.Fprintf(env.Stdout, "Date:        %s\n", when)
+		fmt.Fprintf(env.Stdout, "Description: %s\n", req.Description)
+		ok, err := shared.ConfirmPrompt(env.Stdin, env.Stdout, "Log this worklog?")
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return core.Errorf(core.ExitUsage, "jira.log:no confirmation given, use --yes to log without one")
+		} else if err != nil {
+			return core.WithCode(core.ExitError, err)
+		}
+		if !ok {
+			logrus.Info("Nothing was logged.")
+			return nil
+		}
+	}
+
+	worklog, err := atl.NewWorklog(ctx, req)
+	if err != nil {
+		return core.WithCode(core.ExitAPI, err)
+	}
+	logrus.Infof("Logged %s to %s on %s (worklog %d).", shared.FormatMinutes(worklog.TimeSpentSeconds/60),
+		issue.Key, worklog.StartDate, worklog.TempoWorklogID)
+	return nil
+}
+
+// newRequest builds the worklog from the arguments, the date defaulting to the day of now.
+func newRequest(now time.Time) (atlassian.WorklogRequest, error) {
+	req := atlassian.WorklogRequest{
+		IssueKey:    strings.ToUpper(strings.TrimSpace(args.Get(0))),
+		StartDate:   now.Format(shared.DateLayout),
+		Description: strings.TrimSpace(args.Get(2)),
+	}
+	spent, err := shared.ParseDuration(args.Get(1))
+	if err != nil {
+		return req, err
+	}
+	req.TimeSpentSeconds = int(spent / time.Second)
+
+	if *date != "" {
+		day, err := time.Parse(shared.DateLayout, *date)
+		if err != nil {
+			return req, fmt.Errorf("jira.log:--date %q is not a %s date", *date, shared.DateLayout)
+		}
+		req.StartDate = day.Format(shared.DateLayout)
+	}
+	if *start != "" {
+		at, err := time.Parse("15:04", *start)
+		if err != nil {
+			return req, fmt.Errorf("jira.log:--start %q is not a HH:MM time", *start)
+		}
+		req.StartTime = at.Format("15:04:05")
+	}
+	if req.Description == "" {
+		// Tempo's own default for a worklog logged without a description.
+		req.Description = fmt.Sprintf("Working on issue %s", req.IssueKey)
+	}
+	return req, nil
+}
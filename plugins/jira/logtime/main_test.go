@@ -0,0 +1,76 @@
+package logtime
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/josh5276/halp/core"
+	"github.com/josh5276/halp/shared/atlassian/fake"
+)
+
+// testRun runs `halp jira log` against the fake server, answering the
+// confirmation with stdin.
+func testRun(t *testing.T, srv *fake.Server, stdin string, args ...string) (string, error) {
+	return fake.Run(t, srv, SubPlugin, "jira log", core.Env{Stdin: strings.NewReader(stdin)}, args...)
+}
+
+func TestPlugin_log(t *testing.T) {
+	srv := fake.Start(t, fake.SharedFixture())
+	defer srv.Close()
+
+	out, err := testRun(t, srv, "y\n", "ntc-1", "1h 30m", "Design review", "--date", "2020-10-06", "--start", "9:15")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "NTC-1 [NTC] DELIVER network automation") || !strings.Contains(out, "1h 30m") ||
+		!strings.Contains(out, "2020-10-06 09:15") {
+		t.Fatalf("ERROR: unexpected confirmation\n%s", out)
+	}
+	if len(srv.Logged) != 1 {
+		t.Fatalf("ERROR: expected one worklog to be logged, got %d", len(srv.Logged))
+	}
+	req := srv.Logged[0]
+	if req.IssueKey != "NTC-1" || req.TimeSpentSeconds != 5400 || req.StartDate != "2020-10-06" ||
+		req.StartTime != "09:15:00" || req.Description != "Design review" || req.AuthorAccountID != "abc123" {
+		t.Fatalf("ERROR: unexpected worklog %+v", req)
+	}
+
+	// Declining logs nothing, --yes skips the question.
+	if _, err := testRun(t, srv, "n\n", "NTC-2", "30m"); err != nil || len(srv.Logged) != 1 {
+		t.Fatalf("ERROR: expected nothing to be logged when declined, got %v", err)
+	}
+	if _, err := testRun(t, srv, "", "NTC-2", "0:30", "--yes"); err != nil || len(srv.Logged) != 2 {
+		t.Fatalf("ERROR: expected --yes to log without asking, got %v", err)
+	}
+	if srv.Logged[1].Description != "Working on issue NTC-2" {
+		t.Fatalf("ERROR: expected the default description, got %q", srv.Logged[1].Description)
+	}
+	t.Logf("SUCCESS: logged %+v", req)
+}
+
+func TestPlugin_logErrors(t *testing.T) {
+	srv := fake.Start(t, fake.SharedFixture())
+	defer srv.Close()
+
+	var tests = []struct {
+		name string
+		args []string
+		code core.ExitCode
+	}{
+		{"no duration", []string{"NTC-1"}, core.ExitUsage},
+		{"no unit", []string{"NTC-1", "90"}, core.ExitUsage},
+		{"bad date", []string{"NTC-1", "1h", "--date", "10/06/2020"}, core.ExitUsage},
+		{"bad start", []string{"NTC-1", "1h", "--start", "9am"}, core.ExitUsage},
+		{"no confirmation", []string{"NTC-1", "1h"}, core.ExitUsage},
+		{"unknown issue", []string{"NTC-404", "1h", "--yes"}, core.ExitAPI},
+	}
+	for _, test := range tests {
+		if _, err := testRun(t, srv, "", test.args...); core.Code(err) != test.code {
+			t.Errorf("ERROR: %s: expected exit code %d, got %v", test.name, test.code, err)
+		}
+	}
+	if len(srv.Logged) != 0 {
+		t.Fatalf("ERROR: expected nothing to be logged, got %+v", srv.Logged)
+	}
+	t.Logf("SUCCESS: %d invalid command lines rejected", len(tests))
+}
@@ -0,0 +1,278 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jokelyo/argparse"
+	"github.com/josh5276/halp/core/keyring"
+	"github.com/josh5276/halp/shared"
+	"github.com/josh5276/halp/shared/atlassian"
+
+	"github.com/josh5276/halp/core"
+)
+
+const (
+	// querySection holds the saved queries run with @NAME, e.g.
+	//
+	//	[search.queries]
+	//	team    = project = NTC AND resolution = Unresolved ORDER BY priority DESC
+	//	blocked = assignee = currentUser() AND status = Blocked
+	querySection = "search.queries"
+	// defaultColumns are shown when --columns is not given.
+	defaultColumns = "key,type,status,priority,assignee,summary"
+	// timeLayout is how the created and updated times are shown.
+	timeLayout = "2006-01-02 15:04"
+)
+
+// builtins are the saved queries every install has, the settings can override them.
+var builtins = map[string]string{
+	"mine":     "assignee = currentUser() AND resolution = Unresolved ORDER BY updated DESC",
+	"sprint":   "assignee = currentUser() AND sprint in openSprints() ORDER BY status",
+	"reported": "reporter = currentUser() AND resolution = Unresolved ORDER BY created DESC",
+	"recent":   "assignee = currentUser() AND updated >= -7d ORDER BY updated DESC",
+}
+
+// column is a column --columns can pick, with the value it takes from an issue.
+type column struct {
+	name  string
+	col   shared.Column
+	value func(issue atlassian.JIRAIssue, loc *time.Location) interface{}
+}
+
+// columns are the columns --columns can pick, in the order they are listed in the help.
+var columns = []column{
+	{"key", shared.Column{Name: "KEY"}, func(i atlassian.JIRAIssue, _ *time.Location) interface{} {
+		return i.Key
+	}},
+	{"summary", shared.Column{Name: "SUMMARY"}, func(i atlassian.JIRAIssue, _ *time.Location) interface{} {
+		return i.Fields.Summary
+	}},
+	{"status", shared.Column{Name: "STATUS"}, func(i atlassian.JIRAIssue, _ *time.Location) interface{} {
+		return i.Fields.Status.Name
+	}},
+	{"priority", shared.Column{Name: "PRIORITY"}, func(i atlassian.JIRAIssue, _ *time.Location) interface{} {
+		return i.Fields.Priority.Name
+	}},
+	{"assignee", shared.Column{Name: "ASSIGNEE"}, func(i atlassian.JIRAIssue, _ *time.Location) interface{} {
+		if i.Fields.Assignee == nil {
+			return nil
+		}
+		return i.Fields.Assignee.DisplayName
+	}},
+	{"project", shared.Column{Name: "PROJECT"}, func(i atlassian.JIRAIssue, _ *time.Location) interface{} {
+		return i.Fields.Project.Key
+	}},
+	{"type", shared.Column{Name: "TYPE"}, func(i atlassian.JIRAIssue, _ *time.Location) interface{} {
+		return i.Fields.IssueType.Name
+	}},
+	{"labels", shared.Column{Name: "LABELS"}, func(i atlassian.JIRAIssue, _ *time.Location) interface{} {
+		if len(i.Fields.Labels) == 0 {
+			return nil
+		}
+		return i.Fields.Labels
+	}},
+	{"created", shared.Column{Name: "CREATED", Format: formatTime}, func(i atlassian.JIRAIssue, loc *time.Location) interface{} {
+		return inLocation(i.Fields.Created, loc)
+	}},
+	{"updated", shared.Column{Name: "UPDATED", Format: formatTime}, func(i atlassian.JIRAIssue, loc *time.Location) interface{} {
+		return inLocation(i.Fields.Updated, loc)
+	}},
+}
+
+var (
+	// args holds the JQL, or the @NAME of a saved query.
+	args *core.Args
+	// columnList picks the columns shown and limit the most issues read.
+	columnList *string
+	limit      *int
+)
+
+// SubPlugin function will return a argparse.Command type back to the parent parser
+// nolint:typecheck
+func SubPlugin(p *argparse.Command) core.Plugin {
+	args = core.Positional("[JQL]")
+	cmd := p.NewCommand("search", fmt.Sprintf("Search JIRA issues with a JQL query or a saved one: search %s, "+
+		"e.g. search \"project = NTC AND status = Blocked\" or search @mine. List the saved queries "+
+		"without a query, add your own to the [%s] section of settings.ini.", args.Usage(), querySection))
+	columnList = cmd.String("", "columns", &argparse.Options{
+		Help:    "Comma separated columns to show, out of " + strings.Join(columnNames(), ", "),
+		Default: defaultColumns,
+	})
+	limit = cmd.Int("", "limit", &argparse.Options{Help: "Most issues to show, 0 shows them all", Default: 50})
+	return core.Plugin{CMD: cmd, Func: pluginFunc, Args: args, Complete: completeQueries}
+}
+
+// completeQueries completes the @NAME of the saved queries.
+func completeQueries(env core.Env) ([]string, error) {
+	names := make([]string, 0)
+	for name := range queries(env.Settings) {
+		names = append(names, "@"+name)
+	}
+	return names, nil
+}
+
+// pluginFunc function is executed from the caller
+func pluginFunc(ctx context.Context, env core.Env) error {
+	query := strings.TrimSpace(args.Get(0))
+	if query == "" {
+		return shared.Render(env.Stdout, env.Output, queryReport(env.Settings))
+	}
+	jql, err := resolve(env.Settings, query)
+	if err != nil {
+		return core.WithCode(core.ExitUsage, err)
+	}
+	picked, err := pick(*columnList)
+	if err != nil {
+		return core.WithCode(core.ExitUsage, err)
+	}
+	if *limit < 0 {
+		return core.Errorf(core.ExitUsage, "--limit %d can't be negative", *limit)
+	}
+	loc, err := env.Settings.Location()
+	if err != nil {
+		return core.WithCode(core.ExitConfig, err)
+	}
+	atl, err := env.Atlassian()
+	if err != nil {
+		return core.WithCode(core.ExitConfig, err)
+	}
+
+	issues, total, err := atl.Search(ctx, jql, atlassian.SearchOptions{Limit: *limit})
+	if err != nil {
+		return core.WithCode(core.ExitAPI, err)
+	}
+	return shared.Render(env.Stdout, env.Output, report(issues, total, picked, loc))
+}
+
+// queries returns the saved queries by name, the ones in the settings over the built-ins.
+func queries(cfg keyring.Settings) map[string]string {
+	saved := make(map[string]string, len(builtins))
+	for name, jql := range builtins {
+		saved[name] = jql
+	}
+	for _, name := range cfg.Keys(querySection) {
+		saved[name] = cfg.Value(querySection, name)
+	}
+	return saved
+}
+
+// resolve returns the JQL of a query, looking it up among the saved queries
+// when it starts with @.
+func resolve(cfg keyring.Settings, query string) (string, error) {
+	if !strings.HasPrefix(query, "@") {
+		return query, nil
+	}
+	name := strings.ToLower(strings.TrimPrefix(query, "@"))
+	saved := queries(cfg)
+	if jql := saved[name]; jql != "" {
+		return jql, nil
+	}
+	names := make([]string, 0, len(saved))
+	for n := range saved {
+		names = append(names, "@"+n)
+	}
+	sort.Strings(names)
+	return "", fmt.Errorf("JIRA:Search:no saved query %q, use one of %s or add it to the [%s] section of the settings",
+		query, strings.Join(names, ", "), querySection)
+}
+
+// pick looks up the comma separated names given with --columns.
+func pick(list string) ([]column, error) {
+	picked := make([]column, 0)
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name == "" {
+			continue
+		}
+		found := false
+		for _, c := range columns {
+			if c.name == name {
+				picked = append(picked, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("--columns has an unknown column %q, use %s", name, strings.Join(columnNames(), ", "))
+		}
+	}
+	if len(picked) == 0 {
+		return nil, fmt.Errorf("--columns needs at least one of %s", strings.Join(columnNames(), ", "))
+	}
+	return picked, nil
+}
+
+// report lists the issues found with the columns picked.
+func report(issues []atlassian.JIRAIssue, total int, picked []column, loc *time.Location) shared.Report {
+	r := shared.Report{Columns: make([]shared.Column, 0, len(picked))}
+	for _, c := range picked {
+		r.Columns = append(r.Columns, c.col)
+	}
+	for _, issue := range issues {
+		row := make([]interface{}, 0, len(picked))
+		for _, c := range picked {
+			row = append(row, c.value(issue, loc))
+		}
+		r.Rows = append(r.Rows, row)
+	}
+	switch {
+	case total == 0:
+		r.Notes = append(r.Notes, "No issues found")
+	case total < 0:
+		// The search of JIRA Cloud doesn't count the issues.
+		r.Notes = append(r.Notes, fmt.Sprintf("Showing the first %d issues, raise --limit to see more", len(issues)))
+	case len(issues) < total:
+		r.Notes = append(r.Notes, fmt.Sprintf("Showing %d of %d issues, raise --limit to see more", len(issues), total))
+	}
+	return r
+}
+
+// queryReport lists the saved queries, and whether they are built-in or come from the settings.
+func queryReport(cfg keyring.Settings) shared.Report {
+	r := shared.Report{
+		Title:   "Saved queries",
+		Columns: []shared.Column{{Name: "NAME"}, {Name: "JQL"}, {Name: "SOURCE"}},
+	}
+	saved := queries(cfg)
+	names := make([]string, 0, len(saved))
+	for name := range saved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		source := "built-in"
+		if cfg.Value(querySection, name) != "" {
+			source = "settings"
+		}
+		r.Rows = append(r.Rows, []interface{}{"@" + name, saved[name], source})
+	}
+	return r
+}
+
+// columnNames returns the names --columns accepts.
+func columnNames() []string {
+	names := make([]string, 0, len(columns))
+	for _, c := range columns {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// inLocation parses a JIRA timestamp into the configured timezone, nil when there is none.
+func inLocation(s string, loc *time.Location) interface{} {
+	t := atlassian.ParseTime(s)
+	if t.IsZero() {
+		return nil
+	}
+	return t.In(loc)
+}
+
+func formatTime(v interface{}) string {
+	if t, ok := v.(time.Time); ok {
+		return t.Format(timeLayout)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,138 @@
+package search
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/josh5276/halp/core"
+	"github.com/josh5276/halp/shared/atlassian"
+	"github.com/josh5276/halp/shared/atlassian/fake"
+)
+
+// testRun runs `halp jira search` with the settings in cfg against the fake server
+// and returns what it printed.
+func testRun(t *testing.T, srv *fake.Server, cfg string, args ...string) (string, error) {
+	settings := fake.Settings(t, cfg)
+	settings.Timezone = "America/Chicago"
+	return fake.Run(t, srv, SubPlugin, "jira search", core.Env{Settings: settings}, args...)
+}
+
+func TestPlugin_search(t *testing.T) {
+	srv := fake.Start(t, "testdata/fixture.json")
+	defer srv.Close()
+
+	out, err := testRun(t, srv, "", "@mine", "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Rows []map[string]interface{}
+	}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("ERROR: decoding %s:%s", out, err)
+	}
+	// The unresolved issues assigned to the fake user, NTC-2 is done.
+	if len(doc.Rows) != 2 || doc.Rows[0]["key"] != "NTC-1" || doc.Rows[0]["type"] != "Story" ||
+		doc.Rows[1]["key"] != "OPS-7" || doc.Rows[1]["assignee"] != "Fake User" || doc.Rows[1]["status"] != "Blocked" {
+		t.Fatalf("ERROR: unexpected issues for @mine %s", out)
+	}
+
+	// Over the first of two pages, with the columns picked.
+	out, err = testRun(t, srv, "", "project = NTC", "--columns", "key, labels,updated", "--limit", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"| KEY   | LABELS              | UPDATED          |",
+		"| NTC-1 | automation, backups | 2020-10-02 10:30 |",
+		"Showing 1 of 3 issues",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("ERROR: search is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "SUMMARY") {
+		t.Errorf("ERROR: expected only the columns picked:\n%s", out)
+	}
+	// The enhanced search of JIRA Cloud doesn't count the issues.
+	srv.JiraSearch = atlassian.SearchEnhanced
+	out, err = testRun(t, srv, "", "project = NTC", "--columns", "key", "--limit", "1")
+	if err != nil || !strings.Contains(out, "Showing the first 1 issues") {
+		t.Fatalf("ERROR: expected the first issue, %v:\n%s", err, out)
+	}
+	srv.JiraSearch = ""
+
+	out, err = testRun(t, srv, "", "project = NTC", "--columns", "key,labels", "--limit", "1", "-o", "csv")
+	if err != nil || out != "key,labels\nNTC-1,\"automation, backups\"\n" {
+		t.Fatalf("ERROR: expected the labels to be joined, %v: %q", err, out)
+	}
+	t.Logf("SUCCESS: searched with JQL and a saved query")
+}
+
+func TestPlugin_searchQueries(t *testing.T) {
+	srv := fake.Start(t, "testdata/fixture.json")
+	defer srv.Close()
+
+	cfg := "[search.queries]\nblocked = status = Blocked\nmine = assignee = currentUser() AND project = NTC\n"
+	out, err := testRun(t, srv, cfg, "@Blocked", "-o", "csv", "--columns", "key,project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "key,project\nOPS-7,OPS\n" {
+		t.Fatalf("ERROR: unexpected issues for @blocked %q", out)
+	}
+	// The settings override the built-in queries.
+	out, err = testRun(t, srv, cfg, "@mine", "-o", "csv", "--columns", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "key\nNTC-1\nNTC-2\n" {
+		t.Fatalf("ERROR: expected the @mine of the settings, got %q", out)
+	}
+
+	// Without a query the saved ones are listed.
+	out, err = testRun(t, srv, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"@blocked", "| @mine     | assignee = currentUser() AND project = NTC ",
+		"@sprint", "built-in"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("ERROR: saved queries are missing %q:\n%s", want, out)
+		}
+	}
+
+	names, err := completeQueries(core.Env{Settings: fake.Settings(t, cfg)})
+	if err != nil || len(names) != 5 {
+		t.Fatalf("ERROR: expected 5 saved queries to complete, got %v (%v)", names, err)
+	}
+	t.Logf("SUCCESS: listed, completed and ran the saved queries")
+}
+
+func TestPlugin_searchErrors(t *testing.T) {
+	srv := fake.Start(t, "testdata/fixture.json")
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+		code core.ExitCode
+	}{
+		{name: "unknown query", args: []string{"@nope"}, want: "use one of @mine, @recent", code: core.ExitUsage},
+		{name: "unknown column", args: []string{"@mine", "--columns", "key,color"}, want: `unknown column "color"`,
+			code: core.ExitUsage},
+		{name: "negative limit", args: []string{"@mine", "--limit", "-1"}, want: "can't be negative", code: core.ExitUsage},
+		{name: "bad JQL", args: []string{"fixVersion = 1.0"}, want: "unsupported JQL", code: core.ExitAPI},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testRun(t, srv, "", tt.args...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) || core.Code(err) != tt.code {
+				t.Fatalf("ERROR: expected exit code %d with %q, got %v (%d)", tt.code, tt.want, err, core.Code(err))
+			}
+			t.Logf("SUCCESS: %s failed with %v", tt.name, err)
+		})
+	}
+}
@@ -0,0 +1,295 @@
+// Package timer times the work on an issue locally and logs it to Tempo when
+// the timer is stopped. The running timer and the entries that could not be
+// logged yet are kept in timer.json next to settings.ini.
+package timer
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jokelyo/argparse"
+	"github.com/josh5276/halp/shared"
+	"github.com/josh5276/halp/shared/atlassian"
+	"github.com/sirupsen/logrus"
+
+	"github.com/josh5276/halp/core"
+)
+
+var (
+	// startArgs and switchArgs hold the issue key and the optional note.
+	startArgs  *core.Args
+	switchArgs *core.Args
+	// queue keeps the stopped entry in the queue instead of logging it.
+	queue *bool
+
+	// now is the clock of the timer, the tests replace it.
+	now = time.Now
+)
+
+var issueKey = regexp.MustCompile(`^[A-Z][A-Z0-9_]*-[0-9]+$`)
+
+// SubPlugin function will return a argparse.Command type back to the parent parser
+// nolint:typecheck
+func SubPlugin(p *argparse.Command) core.Plugin {
+	return core.Group("timer", "Time the work on an issue and log it to Tempo when done.",
+		startPlugin,
+		stopPlugin,
+		statusPlugin,
+		switchPlugin,
+		syncPlugin,
+	)(p)
+}
+
+func startPlugin(p *argparse.Command) core.Plugin {
+	startArgs = core.Positional("ISSUE", "[NOTE]")
+	cmd := p.NewCommand("start", fmt.Sprintf("Start timing work: start %s.", startArgs.Usage()))
+	return core.Plugin{CMD: cmd, Func: startFunc, Args: startArgs}
+}
+
+func stopPlugin(p *argparse.Command) core.Plugin {
+	cmd := p.NewCommand("stop", "Stop the timer and log the time spent to Tempo.")
+	queue = cmd.Flag("", "queue", &argparse.Options{Help: "Keep the entry for timer sync instead of logging it now"})
+	return core.Plugin{CMD: cmd, Func: stopFunc}
+}
+
+func statusPlugin(p *argparse.Command) core.Plugin {
+	cmd := p.NewCommand("status", "Show the running timer and the entries waiting to be logged.")
+	return core.Plugin{CMD: cmd, Func: statusFunc}
+}
+
+func switchPlugin(p *argparse.Command) core.Plugin {
+	switchArgs = core.Positional("ISSUE", "[NOTE]")
+	cmd := p.NewCommand("switch", fmt.Sprintf("Stop the timer and start it on another issue: switch %s.", switchArgs.Usage()))
+	return core.Plugin{CMD: cmd, Func: switchFunc, Args: switchArgs}
+}
+
+func syncPlugin(p *argparse.Command) core.Plugin {
+	cmd := p.NewCommand("sync", "Log the entries that were queued or failed to log.")
+	return core.Plugin{CMD: cmd, Func: syncFunc}
+}
+
+// startFunc function is executed from the caller
+func startFunc(_ context.Context, env core.Env) error {
+	next, err := newEntry(startArgs)
+	if err != nil {
+		return core.WithCode(core.ExitUsage, err)
+	}
+	s, path, err := open(env)
+	if err != nil {
+		return err
+	}
+	if s.Running != nil {
+		return core.Errorf(core.ExitUsage, "jira.timer:a timer is already running on %s, stop it or use halp jira timer switch",
+			s.Running.Issue)
+	}
+	s.Running = &next
+	if err := s.save(path); err != nil {
+		return err
+	}
+	logrus.Infof("Started the timer on %s.", next.Issue)
+	return nil
+}
+
+// stopFunc function is executed from the caller
+func stopFunc(ctx context.Context, env core.Env) error {
+	s, path, err := open(env)
+	if err != nil {
+		return err
+	}
+	if s.Running == nil {
+		return core.Errorf(core.ExitUsage, "jira.timer:no timer is running, start one with halp jira timer start")
+	}
+	stopped := s.stop()
+	if err := s.save(path); err != nil {
+		return err
+	}
+	if stopped == nil {
+		return nil
+	}
+	if *queue {
+		logrus.Infof("Queued %s on %s, log it with halp jira timer sync.",
+			shared.FormatMinutes(stopped.request(time.UTC).TimeSpentSeconds/60), stopped.Issue)
+		return nil
+	}
+	return flush(ctx, env, s, path, func(e entry) bool { return e == *stopped })
+}
+
+// switchFunc function is executed from the caller
+func switchFunc(ctx context.Context, env core.Env) error {
+	next, err := newEntry(switchArgs)
+	if err != nil {
+		return core.WithCode(core.ExitUsage, err)
+	}
+	s, path, err := open(env)
+	if err != nil {
+		return err
+	}
+	// Switching without a running timer simply starts one.
+	var stopped *entry
+	if s.Running != nil {
+		stopped = s.stop()
+		// Start at the very moment the previous timer stopped.
+		if stopped != nil {
+			next.Started = stopped.Stopped
+		}
+	}
+	s.Running = &next
+	if err := s.save(path); err != nil {
+		return err
+	}
+	logrus.Infof("Started the timer on %s.", next.Issue)
+	if stopped == nil {
+		return nil
+	}
+	return flush(ctx, env, s, path, func(e entry) bool { return e == *stopped })
+}
+
+// statusFunc function is executed from the caller
+func statusFunc(_ context.Context, env core.Env) error {
+	s, _, err := open(env)
+	if err != nil {
+		return err
+	}
+	loc, err := env.Settings.Location()
+	if err != nil {
+		return core.WithCode(core.ExitConfig, err)
+	}
+
+	r := shared.Report{
+		Title: "Timer",
+		Columns: []shared.Column{
+			{Name: "STATE"},
+			{Name: "JIRA ID"},
+			{Name: "STARTED", Format: func(v interface{}) string {
+				return v.(time.Time).Format("2006-01-02 15:04")
+			}},
+			{Name: "HOURS SPENT", Key: "minutes", Format: shared.FormatMinutes},
+			{Name: "NOTE"},
+			{Name: "ERROR"},
+		},
+	}
+	current := now()
+	if s.Running != nil {
+		r.Rows = append(r.Rows, []interface{}{"running", s.Running.Issue, s.Running.Started.In(loc),
+			int(s.Running.elapsed(current) / time.Minute), s.Running.Note, ""})
+	} else {
+		r.Notes = append(r.Notes, "No timer is running")
+	}
+	for _, e := range s.Queue {
+		r.Rows = append(r.Rows, []interface{}{"queued", e.Issue, e.Started.In(loc),
+			e.request(loc).TimeSpentSeconds / 60, e.Note, e.Error})
+	}
+	if len(s.Queue) > 0 {
+		r.Notes = append(r.Notes, fmt.Sprintf("%d entries are queued, log them with halp jira timer sync", len(s.Queue)))
+	}
+	return shared.Render(env.Stdout, env.Output, r)
+}
+
+// syncFunc function is executed from the caller
+func syncFunc(ctx context.Context, env core.Env) error {
+	s, path, err := open(env)
+	if err != nil {
+		return err
+	}
+	if len(s.Queue) == 0 {
+		logrus.Info("Nothing to sync, the queue is empty.")
+		return nil
+	}
+	return flush(ctx, env, s, path, func(entry) bool { return true })
+}
+
+// newEntry starts an entry now on the issue and with the note of the arguments.
+func newEntry(args *core.Args) (entry, error) {
+	key := strings.ToUpper(strings.TrimSpace(args.Get(0)))
+	if !issueKey.MatchString(key) {
+		return entry{}, fmt.Errorf("jira.timer:%q is not an issue key such as NTC-1", args.Get(0))
+	}
+	return entry{Issue: key, Note: strings.TrimSpace(args.Get(1)), Started: now().Truncate(time.Second)}, nil
+}
+
+// open loads the timer state kept next to settings.ini, returning it with its path.
+func open(env core.Env) (*state, string, error) {
+	dir := env.Settings.Dir()
+	if dir == "" {
+		return nil, "", core.Errorf(core.ExitConfig, "jira.timer:no settings directory to keep the timer in")
+	}
+	path := filepath.Join(dir, stateFile)
+	s, err := loadState(path)
+	if err != nil {
+		return nil, path, core.WithCode(core.ExitConfig, err)
+	}
+	return s, path, nil
+}
+
+// stop stops the running timer and queues its entry until it is logged. Timers
+// that ran for less than a minute have nothing to log and return nil.
+func (s *state) stop() *entry {
+	stopped := *s.Running
+	stopped.Stopped = now().Truncate(time.Second)
+	s.Running = nil
+	if stopped.request(time.UTC).TimeSpentSeconds == 0 {
+		logrus.Warnf("The timer on %s ran for less than a minute, nothing to log.", stopped.Issue)
+		return nil
+	}
+	s.Queue = append(s.Queue, stopped)
+	return &stopped
+}
+
+// flush logs the queued entries selected by only to Tempo, keeping the ones that
+// failed in the queue along with their error. The state is saved either way.
+func flush(ctx context.Context, env core.Env, s *state, path string, only func(entry) bool) error {
+	loc, err := env.Settings.Location()
+	if err != nil {
+		return core.WithCode(core.ExitConfig, err)
+	}
+	atl, err := env.Atlassian()
+	if err != nil {
+		return core.Errorf(core.ExitConfig, "jira.timer:the time is queued for halp jira timer sync:%w", err)
+	}
+
+	var (
+		kept    = make([]entry, 0, len(s.Queue))
+		failed  int
+		lastErr error
+	)
+	for _, e := range s.Queue {
+		if !only(e) || ctx.Err() != nil {
+			kept = append(kept, e)
+			continue
+		}
+		worklog, err := logEntry(ctx, atl, e.request(loc))
+		if err != nil {
+			e.Error = err.Error()
+			kept = append(kept, e)
+			failed, lastErr = failed+1, err
+			continue
+		}
+		logrus.Infof("Logged %s to %s on %s (worklog %d).", shared.FormatMinutes(worklog.TimeSpentSeconds/60),
+			worklog.Issue.Key, worklog.StartDate, worklog.TempoWorklogID)
+	}
+	s.Queue = kept
+	if err := s.save(path); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if failed > 0 {
+		return core.Errorf(core.ExitAPI, "jira.timer:%d entries could not be logged and are queued for halp jira timer sync:%w",
+			failed, lastErr)
+	}
+	return nil
+}
+
+// logEntry creates the worklog of an entry as the configured account.
+func logEntry(ctx context.Context, atl *atlassian.Client, req atlassian.WorklogRequest) (atlassian.Worklog, error) {
+	var err error
+	if req.AuthorAccountID, err = atl.AccountID(ctx); err != nil {
+		return atlassian.Worklog{}, err
+	}
+	return atl.NewWorklog(ctx, req)
+}
@@ -0,0 +1,131 @@
+package timer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/josh5276/halp/core"
+	"github.com/josh5276/halp/shared/atlassian/fake"
+)
+
+// testRun runs `halp jira timer` against the fake server at the time passed in,
+// with the settings kept in dir.
+func testRun(t *testing.T, srv *fake.Server, dir string, at time.Time, args ...string) (string, error) {
+	now = func() time.Time { return at }
+	defer func() { now = time.Now }()
+
+	settings := fake.Settings(t, "")
+	settings.Source, settings.Timezone = filepath.Join(dir, "settings.ini"), "UTC"
+	return fake.Run(t, srv, SubPlugin, "jira timer", core.Env{Settings: settings}, args...)
+}
+
+func testStatus(t *testing.T, srv *fake.Server, dir string, at time.Time) []map[string]interface{} {
+	out, err := testRun(t, srv, dir, at, "status", "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct{ Rows []map[string]interface{} }
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("ERROR: decoding %s:%s", out, err)
+	}
+	return doc.Rows
+}
+
+func TestPlugin_timer(t *testing.T) {
+	srv := fake.Start(t, fake.SharedFixture())
+	defer srv.Close()
+	dir, err := ioutil.TempDir("", "halp-timer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t0 := time.Date(2020, time.October, 6, 9, 0, 0, 0, time.UTC)
+	if _, err := testRun(t, srv, dir, t0, "start", "ntc-1", "Design review"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testRun(t, srv, dir, t0, "start", "NTC-2"); core.Code(err) != core.ExitUsage {
+		t.Fatalf("ERROR: expected a second timer to be refused, got %v", err)
+	}
+	rows := testStatus(t, srv, dir, t0.Add(10*time.Minute))
+	if len(rows) != 1 || rows[0]["state"] != "running" || rows[0]["jira_id"] != "NTC-1" || rows[0]["minutes"] != 10.0 {
+		t.Fatalf("ERROR: unexpected status %v", rows)
+	}
+
+	// Switching logs the time spent so far and starts timing the next issue.
+	if _, err := testRun(t, srv, dir, t0.Add(45*time.Minute), "switch", "OPS-7"); err != nil {
+		t.Fatal(err)
+	}
+	if len(srv.Logged) != 1 || srv.Logged[0].IssueKey != "NTC-1" || srv.Logged[0].TimeSpentSeconds != 2700 ||
+		srv.Logged[0].StartDate != "2020-10-06" || srv.Logged[0].StartTime != "09:00:00" ||
+		srv.Logged[0].Description != "Design review" || srv.Logged[0].AuthorAccountID != "abc123" {
+		t.Fatalf("ERROR: unexpected worklog %+v", srv.Logged)
+	}
+
+	// A stop that fails to log is queued, and logged by sync.
+	srv.Script(fake.Response{Method: http.MethodPost, Path: "/core/3/worklogs", Status: http.StatusInternalServerError})
+	if _, err := testRun(t, srv, dir, t0.Add(75*time.Minute), "stop"); core.Code(err) != core.ExitAPI {
+		t.Fatalf("ERROR: expected the failed log to be reported, got %v", err)
+	}
+	rows = testStatus(t, srv, dir, t0.Add(80*time.Minute))
+	if len(rows) != 1 || rows[0]["state"] != "queued" || rows[0]["minutes"] != 30.0 || rows[0]["error"] == "" {
+		t.Fatalf("ERROR: expected the failed entry to be queued, got %v", rows)
+	}
+	if _, err := testRun(t, srv, dir, t0.Add(90*time.Minute), "sync"); err != nil {
+		t.Fatal(err)
+	}
+	if len(srv.Logged) != 2 || srv.Logged[1].IssueKey != "OPS-7" || srv.Logged[1].StartTime != "09:45:00" ||
+		srv.Logged[1].Description != "Working on issue OPS-7" {
+		t.Fatalf("ERROR: unexpected worklogs %+v", srv.Logged)
+	}
+	if rows := testStatus(t, srv, dir, t0.Add(90*time.Minute)); len(rows) != 0 {
+		t.Fatalf("ERROR: expected an empty status after the sync, got %v", rows)
+	}
+	if _, err := testRun(t, srv, dir, t0, "stop"); core.Code(err) != core.ExitUsage {
+		t.Fatalf("ERROR: expected stop without a timer to fail, got %v", err)
+	}
+	t.Logf("SUCCESS: logged %+v", srv.Logged)
+}
+
+func TestPlugin_timerQueue(t *testing.T) {
+	srv := fake.NewServer(fake.Fixture{})
+	defer srv.Close()
+	dir, err := ioutil.TempDir("", "halp-timer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A timer stopped within the minute has nothing to log.
+	t0 := time.Date(2020, time.October, 6, 9, 0, 0, 0, time.UTC)
+	steps := []struct {
+		at   time.Time
+		args []string
+	}{
+		{t0, []string{"start", "NTC-2"}},
+		{t0.Add(20 * time.Second), []string{"stop"}},
+		{t0.Add(time.Minute), []string{"start", "NTC-2", "Standup"}},
+		{t0.Add(time.Hour), []string{"stop", "--queue"}},
+	}
+	for _, step := range steps {
+		if _, err := testRun(t, srv, dir, step.at, step.args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rows := testStatus(t, srv, dir, t0.Add(time.Hour))
+	if len(rows) != 1 || rows[0]["state"] != "queued" || rows[0]["minutes"] != 59.0 || rows[0]["note"] != "Standup" {
+		t.Fatalf("ERROR: unexpected queue %v", rows)
+	}
+	if len(srv.Requests) != 0 {
+		t.Fatalf("ERROR: expected nothing to be sent, got %v", srv.Requests)
+	}
+	if _, err := testRun(t, srv, dir, t0, "start", "not-a-key!"); core.Code(err) != core.ExitUsage {
+		t.Fatalf("ERROR: expected an invalid issue key to be refused, got %v", err)
+	}
+	t.Logf("SUCCESS: queued %v", rows)
+}
@@ -0,0 +1,98 @@
+package timer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/josh5276/halp/shared"
+	"github.com/josh5276/halp/shared/atlassian"
+)
+
+// stateFile is the file next to settings.ini the timer keeps its state in.
+const stateFile = "timer.json"
+
+type (
+	// state is the running timer and the entries waiting to be logged.
+	state struct {
+		Running *entry  `json:"running,omitempty"`
+		Queue   []entry `json:"queue"`
+	}
+
+	// entry is a stretch of time spent on an issue. Stopped is zero while the
+	// timer runs, Error holds why logging a queued entry last failed.
+	entry struct {
+		Issue   string    `json:"issue"`
+		Note    string    `json:"note,omitempty"`
+		Started time.Time `json:"started"`
+		Stopped time.Time `json:"stopped,omitempty"`
+		Error   string    `json:"error,omitempty"`
+	}
+)
+
+// loadState reads the timer state from path, an empty state when it does not exist yet.
+func loadState(path string) (*state, error) {
+	s := &state{Queue: make([]entry, 0)}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("jira.timer:%s:%s", path, err)
+	}
+	return s, nil
+}
+
+// save writes the state to path, through a temporary file so that an interrupted
+// write never loses the running timer or the queue.
+func (s *state) save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".timer-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// elapsed is the time spent on the entry, up to now while it is running.
+func (e entry) elapsed(now time.Time) time.Duration {
+	if !e.Stopped.IsZero() {
+		now = e.Stopped
+	}
+	return now.Sub(e.Started)
+}
+
+// request is the worklog the stopped entry is logged as, starting when the timer
+// was started in loc and rounded to the minute.
+func (e entry) request(loc *time.Location) atlassian.WorklogRequest {
+	started := e.Started.In(loc)
+	req := atlassian.WorklogRequest{
+		IssueKey:         e.Issue,
+		TimeSpentSeconds: int(e.Stopped.Sub(e.Started).Round(time.Minute) / time.Second),
+		StartDate:        started.Format(shared.DateLayout),
+		StartTime:        started.Format("15:04:05"),
+		Description:      e.Note,
+	}
+	if req.Description == "" {
+		// Tempo's own default for a worklog logged without a description.
+		req.Description = fmt.Sprintf("Working on issue %s", e.Issue)
+	}
+	return req
+}
@@ -0,0 +1,384 @@
+package view
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jokelyo/argparse"
+	"github.com/josh5276/halp/shared"
+	"github.com/josh5276/halp/shared/atlassian"
+	"github.com/sirupsen/logrus"
+
+	"github.com/josh5276/halp/core"
+)
+
+// timeLayout is how the created and updated times are shown.
+const timeLayout = "2006-01-02 15:04"
+
+type (
+	// document is the issue as written by -o json and -o yaml.
+	document struct {
+		Key           string            `json:"key" yaml:"key"`
+		Summary       string            `json:"summary" yaml:"summary"`
+		Type          string            `json:"type" yaml:"type"`
+		Status        string            `json:"status" yaml:"status"`
+		Priority      string            `json:"priority,omitempty" yaml:"priority,omitempty"`
+		Project       string            `json:"project" yaml:"project"`
+		Assignee      string            `json:"assignee,omitempty" yaml:"assignee,omitempty"`
+		Reporter      string            `json:"reporter,omitempty" yaml:"reporter,omitempty"`
+		Labels        []string          `json:"labels" yaml:"labels"`
+		Created       string            `json:"created" yaml:"created"`
+		Updated       string            `json:"updated" yaml:"updated"`
+		Parent        *link             `json:"parent,omitempty" yaml:"parent,omitempty"`
+		CustomFields  map[string]string `json:"custom_fields,omitempty" yaml:"custom_fields,omitempty"`
+		Description   string            `json:"description" yaml:"description"`
+		Subtasks      []link            `json:"subtasks" yaml:"subtasks"`
+		Links         []link            `json:"links" yaml:"links"`
+		Comments      []comment         `json:"comments" yaml:"comments"`
+		CommentsTotal int               `json:"comments_total" yaml:"comments_total"`
+		Worklogs      []worklog         `json:"worklogs" yaml:"worklogs"`
+	}
+
+	// link is an issue referenced by the one viewed, Relation is left empty for
+	// the parent and sub-tasks.
+	link struct {
+		Relation string `json:"relation,omitempty" yaml:"relation,omitempty"`
+		Key      string `json:"key" yaml:"key"`
+		Status   string `json:"status" yaml:"status"`
+		Summary  string `json:"summary" yaml:"summary"`
+	}
+
+	// comment is a comment on the issue, its body as plain text.
+	comment struct {
+		Author  string `json:"author" yaml:"author"`
+		Created string `json:"created" yaml:"created"`
+		Body    string `json:"body" yaml:"body"`
+	}
+
+	// worklog is time logged to the issue in Tempo.
+	worklog struct {
+		Date            string `json:"date" yaml:"date"`
+		Author          string `json:"author" yaml:"author"`
+		Minutes         int    `json:"minutes" yaml:"minutes"`
+		BillableMinutes int    `json:"billable_minutes" yaml:"billable_minutes"`
+		Description     string `json:"description" yaml:"description"`
+	}
+)
+
+var (
+	// args holds the key of the issue to view.
+	args *core.Args
+	// web opens the issue in the browser instead.
+	web *bool
+
+	// openBrowser opens an address in the default browser, the tests record it instead.
+	openBrowser = browser
+)
+
+// SubPlugin function will return a argparse.Command type back to the parent parser
+// nolint:typecheck
+func SubPlugin(p *argparse.Command) core.Plugin {
+	args = core.Positional("ISSUE")
+	cmd := p.NewCommand("view", fmt.Sprintf("View a JIRA issue with its description, comments, sub-tasks, "+
+		"links and worklogs: view %s, e.g. view NTC-1.", args.Usage()))
+	web = cmd.Flag("", "web", &argparse.Options{Help: "Open the issue in the browser instead"})
+	return core.Plugin{CMD: cmd, Func: pluginFunc, Args: args}
+}
+
+// pluginFunc function is executed from the caller
+func pluginFunc(ctx context.Context, env core.Env) error {
+	key := strings.ToUpper(strings.TrimSpace(args.Get(0)))
+	switch env.Output {
+	case shared.FormatCSV, shared.FormatMarkdown:
+		return core.Errorf(core.ExitUsage, "jira.view:an issue can't be shown as %s, use -o table, json or yaml", env.Output)
+	}
+	loc, err := env.Settings.Location()
+	if err != nil {
+		return core.WithCode(core.ExitConfig, err)
+	}
+
+	// The address only takes the JIRA URL, the browser signs in on its own.
+	if *web {
+		address, err := atlassian.BrowseURL(env.Settings, key)
+		if err != nil {
+			return core.WithCode(core.ExitConfig, err)
+		}
+		logrus.Infof("Opening %s", address)
+		if err := openBrowser(address); err != nil {
+			return core.Errorf(core.ExitError, "JIRA:View:openBrowser:%s, open %s yourself", err, address)
+		}
+		return nil
+	}
+
+	atl, err := env.Atlassian()
+	if err != nil {
+		return core.WithCode(core.ExitConfig, err)
+	}
+
+	issue, err := atl.IssueDetail(ctx, key)
+	if err != nil {
+		return core.WithCode(core.ExitAPI, err)
+	}
+	// The issue is still worth showing when Tempo can't be reached.
+	worklogs, worklogErr := atl.IssueWorklogs(ctx, key)
+	if worklogErr != nil {
+		logrus.Warnf("Could not fetch the worklogs of %s: %s", key, worklogErr)
+	}
+	sort.SliceStable(worklogs, func(i, j int) bool {
+		return worklogs[i].StartDate+worklogs[i].StartTime < worklogs[j].StartDate+worklogs[j].StartTime
+	})
+	if env.Output != shared.FormatTable {
+		return shared.Encode(env.Stdout, env.Output, newDocument(issue, worklogs, loc))
+	}
+
+	printIssue(env.Stdout, issue, loc)
+	if worklogErr != nil {
+		return nil
+	}
+	heading(env.Stdout, "Worklogs")
+	if len(worklogs) == 0 {
+		fmt.Fprintln(env.Stdout, "Nothing logged yet.")
+		return nil
+	}
+	return shared.Render(env.Stdout, shared.FormatTable, worklogReport(worklogs))
+}
+
+// printIssue writes the issue as text, its fields first and then every section it has.
+func printIssue(w io.Writer, issue atlassian.IssueDetail, loc *time.Location) {
+	f := issue.Fields
+	fmt.Fprintf(w, "%s  %s\n\n", issue.Key, f.Summary)
+	// Only a missing assignee is worth calling out, the row is left out without a reporter.
+	reporter := ""
+	if f.Reporter != nil {
+		reporter = userName(f.Reporter)
+	}
+	fields := [][2]string{
+		{"Type", f.IssueType.Name},
+		{"Status", f.Status.Name},
+		{"Priority", f.Priority.Name},
+		{"Project", fmt.Sprintf("%s (%s)", f.Project.Name, f.Project.Key)},
+		{"Assignee", userName(f.Assignee)},
+		{"Reporter", reporter},
+		{"Labels", strings.Join(f.Labels, ", ")},
+		{"Created", formatTime(f.Created, loc)},
+		{"Updated", formatTime(f.Updated, loc)},
+	}
+	if f.Parent != nil {
+		fields = append(fields, [2]string{"Parent", linked(*f.Parent)})
+	}
+	for _, field := range fields {
+		if field[1] != "" {
+			fmt.Fprintf(w, "%-10s %s\n", field[0]+":", field[1])
+		}
+	}
+
+	if custom := customFields(issue); len(custom) > 0 {
+		heading(w, "Custom fields")
+		width := 0
+		for _, field := range custom {
+			if len(field[0]) > width {
+				width = len(field[0])
+			}
+		}
+		for _, field := range custom {
+			fmt.Fprintf(w, "%-*s %s\n", width+1, field[0]+":", field[1])
+		}
+	}
+	if description := atlassian.Text(f.Description); description != "" {
+		heading(w, "Description")
+		fmt.Fprintln(w, description)
+	}
+	if len(f.Subtasks) > 0 {
+		heading(w, "Sub-tasks")
+		for _, subtask := range f.Subtasks {
+			fmt.Fprintln(w, linked(subtask))
+		}
+	}
+	if len(f.IssueLinks) > 0 {
+		heading(w, "Links")
+		for _, link := range f.IssueLinks {
+			if link.OutwardIssue != nil {
+				fmt.Fprintf(w, "%s %s\n", link.Type.Outward, linked(*link.OutwardIssue))
+			} else if link.InwardIssue != nil {
+				fmt.Fprintf(w, "%s %s\n", link.Type.Inward, linked(*link.InwardIssue))
+			}
+		}
+	}
+	if comments := f.Comment.Comments; len(comments) > 0 {
+		// JIRA only returns the latest comments of an issue with a long discussion.
+		title := fmt.Sprintf("Comments (%d)", len(comments))
+		if f.Comment.Total > len(comments) {
+			title = fmt.Sprintf("Comments (showing %d of %d)", len(comments), f.Comment.Total)
+		}
+		heading(w, title)
+		for i, comment := range comments {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "%s, %s\n", userName(&comment.Author), formatTime(comment.Created, loc))
+			for _, line := range strings.Split(atlassian.Text(comment.Body), "\n") {
+				fmt.Fprintln(w, strings.TrimRight("  "+line, " "))
+			}
+		}
+	}
+}
+
+// newDocument builds the structured form of the issue and its worklogs.
+func newDocument(issue atlassian.IssueDetail, worklogs []atlassian.Worklog, loc *time.Location) document {
+	f := issue.Fields
+	doc := document{
+		Key:           issue.Key,
+		Summary:       f.Summary,
+		Type:          f.IssueType.Name,
+		Status:        f.Status.Name,
+		Priority:      f.Priority.Name,
+		Project:       f.Project.Key,
+		Labels:        append([]string{}, f.Labels...),
+		Created:       timestamp(f.Created, loc),
+		Updated:       timestamp(f.Updated, loc),
+		Description:   atlassian.Text(f.Description),
+		Subtasks:      make([]link, 0, len(f.Subtasks)),
+		Links:         make([]link, 0, len(f.IssueLinks)),
+		Comments:      make([]comment, 0, len(f.Comment.Comments)),
+		CommentsTotal: f.Comment.Total,
+		Worklogs:      make([]worklog, 0, len(worklogs)),
+	}
+	if f.Assignee != nil {
+		doc.Assignee = userName(f.Assignee)
+	}
+	if f.Reporter != nil {
+		doc.Reporter = userName(f.Reporter)
+	}
+	if f.Parent != nil {
+		parent := newLink("", *f.Parent)
+		doc.Parent = &parent
+	}
+	for _, field := range customFields(issue) {
+		if doc.CustomFields == nil {
+			doc.CustomFields = make(map[string]string)
+		}
+		doc.CustomFields[field[0]] = field[1]
+	}
+	for _, subtask := range f.Subtasks {
+		doc.Subtasks = append(doc.Subtasks, newLink("", subtask))
+	}
+	for _, l := range f.IssueLinks {
+		if l.OutwardIssue != nil {
+			doc.Links = append(doc.Links, newLink(l.Type.Outward, *l.OutwardIssue))
+		} else if l.InwardIssue != nil {
+			doc.Links = append(doc.Links, newLink(l.Type.Inward, *l.InwardIssue))
+		}
+	}
+	for _, c := range f.Comment.Comments {
+		doc.Comments = append(doc.Comments, comment{Author: userName(&c.Author), Created: timestamp(c.Created, loc),
+			Body: atlassian.Text(c.Body)})
+	}
+	if doc.CommentsTotal < len(doc.Comments) {
+		doc.CommentsTotal = len(doc.Comments)
+	}
+	for _, w := range worklogs {
+		doc.Worklogs = append(doc.Worklogs, worklog{Date: w.StartDate, Author: w.Author.DisplayName,
+			Minutes: w.TimeSpentSeconds / 60, BillableMinutes: w.BillableSeconds / 60, Description: w.Description})
+	}
+	return doc
+}
+
+func newLink(relation string, issue atlassian.LinkedIssue) link {
+	return link{Relation: relation, Key: issue.Key, Status: issue.Fields.Status.Name, Summary: issue.Fields.Summary}
+}
+
+// worklogReport lists the worklogs of the issue, sorted by date, with the total logged.
+func worklogReport(worklogs []atlassian.Worklog) shared.Report {
+	r := shared.Report{
+		Columns: []shared.Column{
+			{Name: "DATE"},
+			{Name: "AUTHOR"},
+			{Name: "HOURS SPENT", Key: "minutes", Format: shared.FormatMinutes},
+			{Name: "BILLABLE", Key: "billable_minutes", Format: shared.FormatMinutes},
+			{Name: "DESCRIPTION"},
+		},
+	}
+	var spent, billable int
+	for _, worklog := range worklogs {
+		spent += worklog.TimeSpentSeconds / 60
+		billable += worklog.BillableSeconds / 60
+		r.Rows = append(r.Rows, []interface{}{worklog.StartDate, worklog.Author.DisplayName,
+			worklog.TimeSpentSeconds / 60, worklog.BillableSeconds / 60, worklog.Description})
+	}
+	r.Footer = []interface{}{"Total", nil, spent, billable, nil}
+	return r
+}
+
+// customFields lists the custom fields of the issue that have a value by name, sorted
+// by name. A field JIRA did not name is shown by its id.
+func customFields(issue atlassian.IssueDetail) [][2]string {
+	fields := make([][2]string, 0, len(issue.Custom))
+	for id, value := range issue.Custom {
+		name := issue.Names[id]
+		if name == "" {
+			name = id
+		}
+		fields = append(fields, [2]string{name, atlassian.FieldText(value)})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i][0] < fields[j][0] })
+	return fields
+}
+
+// heading starts a section of the issue.
+func heading(w io.Writer, title string) {
+	fmt.Fprintf(w, "\n%s\n%s\n", title, strings.Repeat("-", len(title)))
+}
+
+// linked describes an issue referenced by the one viewed, e.g. "NTC-3 [Done] Write the runbook".
+func linked(issue atlassian.LinkedIssue) string {
+	return fmt.Sprintf("%s [%s] %s", issue.Key, issue.Fields.Status.Name, issue.Fields.Summary)
+}
+
+// userName is the display name of a user, Unassigned when there is none.
+func userName(u *atlassian.User) string {
+	switch {
+	case u == nil:
+		return "Unassigned"
+	case u.DisplayName != "":
+		return u.DisplayName
+	}
+	return u.AccountID
+}
+
+// formatTime shows a JIRA timestamp in the configured timezone.
+func formatTime(s string, loc *time.Location) string {
+	t := atlassian.ParseTime(s)
+	if t.IsZero() {
+		return s
+	}
+	return t.In(loc).Format(timeLayout)
+}
+
+// timestamp writes a JIRA timestamp as RFC 3339 in the configured timezone.
+func timestamp(s string, loc *time.Location) string {
+	t := atlassian.ParseTime(s)
+	if t.IsZero() {
+		return s
+	}
+	return t.In(loc).Format(time.RFC3339)
+}
+
+// browser opens an address with the default browser of the desktop.
+func browser(address string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", address)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", address)
+	default:
+		cmd = exec.Command("xdg-open", address)
+	}
+	return cmd.Start()
+}
@@ -0,0 +1,117 @@
+package view
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/josh5276/halp/core"
+	"github.com/josh5276/halp/core/keyring"
+	"github.com/josh5276/halp/shared/atlassian/fake"
+)
+
+// testRun runs `halp jira view` against the fake server and returns what it printed.
+func testRun(t *testing.T, srv *fake.Server, args ...string) (string, error) {
+	return fake.Run(t, srv, SubPlugin, "jira view", core.Env{Settings: keyring.Settings{Timezone: "America/Chicago"}}, args...)
+}
+
+func TestPlugin_view(t *testing.T) {
+	srv := fake.Start(t, "testdata/fixture.json")
+	defer srv.Close()
+
+	out, err := testRun(t, srv, "ntc-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "Reporter:") {
+		t.Errorf("ERROR: expected no reporter row for an issue without one:\n%s", out)
+	}
+	for _, want := range []string{
+		"NTC-1  [NTC] DELIVER network automation",
+		"Assignee:  Fake User",
+		"Created:   2020-09-01 09:00",
+		"Custom fields\n-------------\nCustomer:     ACME\nSites:        DFW3, IAD2\nStory Points: 5\n\nDescription",
+		"Scope\n-----\nAutomate the core configs, see the runbook (https://wiki.example.com/runbook).\n• Backups",
+		"NTC-10 [Done] Write the runbook",
+		"blocks OPS-7 [To Do] [OPS] DELIVER on-call",
+		"Comments (showing 1 of 3)\n-------------------------\nFake User, 2020-10-01 09:30\n  Backups are done, `upgrades` next.",
+		"| 2020-10-02 | Fake User | 1h 30m      | 1h 30m   |",
+		"| TOTAL      |           | 3H 45M      | 3H 45M   |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("ERROR: view is missing %q:\n%s", want, out)
+		}
+	}
+
+	// Issues without details have nothing but their fields and worklogs.
+	if out, err = testRun(t, srv, "NTC-2"); err != nil || strings.Contains(out, "Description") || strings.Contains(out, "Custom fields") ||
+		!strings.Contains(out, "| 2020-10-01 | Fake User | 0h 30m      | 0h 0m    | Standup     |") {
+		t.Fatalf("ERROR: unexpected view of NTC-2, %v:\n%s", err, out)
+	}
+	if _, err := testRun(t, srv, "NTC-404"); core.Code(err) != core.ExitAPI {
+		t.Fatalf("ERROR: expected an unknown issue to be an API error, got %v", err)
+	}
+	t.Logf("SUCCESS: viewed NTC-1")
+}
+
+func TestPlugin_viewOutput(t *testing.T) {
+	srv := fake.Start(t, "testdata/fixture.json")
+	defer srv.Close()
+
+	out, err := testRun(t, srv, "NTC-1", "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Key           string
+		Created       string
+		Labels        []string
+		Links         []map[string]string
+		Comments      []map[string]string
+		CommentsTotal int               `json:"comments_total"`
+		CustomFields  map[string]string `json:"custom_fields"`
+		Worklogs      []map[string]interface{}
+	}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("ERROR: decoding %s:%s", out, err)
+	}
+	if doc.Key != "NTC-1" || doc.Created != "2020-09-01T09:00:00-05:00" || len(doc.Labels) != 2 ||
+		len(doc.Links) != 1 || doc.Links[0]["relation"] != "blocks" || doc.Links[0]["key"] != "OPS-7" ||
+		len(doc.Comments) != 1 || doc.Comments[0]["body"] != "Backups are done, `upgrades` next." || doc.CommentsTotal != 3 ||
+		len(doc.Worklogs) == 0 || doc.Worklogs[0]["billable_minutes"] == nil ||
+		len(doc.CustomFields) != 3 || doc.CustomFields["Sites"] != "DFW3, IAD2" {
+		t.Fatalf("ERROR: unexpected document %s", out)
+	}
+
+	if out, err = testRun(t, srv, "NTC-1", "-o", "yaml"); err != nil || !strings.Contains(out, "comments_total: 3") {
+		t.Fatalf("ERROR: expected a YAML document, %v:\n%s", err, out)
+	}
+
+	requests := len(srv.Requests)
+	if _, err := testRun(t, srv, "NTC-1", "-o", "csv"); core.Code(err) != core.ExitUsage || len(srv.Requests) != requests {
+		t.Fatalf("ERROR: expected -o csv to be a usage error before any request, got %v", err)
+	}
+	t.Logf("SUCCESS: viewed NTC-1 as JSON and YAML")
+}
+
+func TestPlugin_viewWeb(t *testing.T) {
+	srv := fake.Start(t, "testdata/fixture.json")
+	defer srv.Close()
+
+	var opened string
+	openBrowser = func(address string) error { opened = address; return nil }
+	defer func() { openBrowser = browser }()
+
+	// Only the JIRA URL of the settings is needed, not the credentials.
+	settings := fake.Settings(t, "[atlassian]\njira_url = https://jira.example.com/\n")
+	if _, err := fake.Run(t, srv, SubPlugin, "jira view", core.Env{Settings: settings}, "ntc-1", "--web"); err != nil {
+		t.Fatal(err)
+	}
+	if opened != "https://jira.example.com/browse/NTC-1" || len(srv.Requests) != 0 {
+		t.Fatalf("ERROR: expected only the browser to open NTC-1, got %q and %v", opened, srv.Requests)
+	}
+	if _, err := testRun(t, srv, "NTC-1", "--web"); core.Code(err) != core.ExitConfig {
+		t.Fatalf("ERROR: expected settings without a JIRA URL to be a config error, got %v", err)
+	}
+	t.Logf("SUCCESS: opened %s", opened)
+}
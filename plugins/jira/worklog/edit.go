@@ -0,0 +1,180 @@
+package worklog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jokelyo/argparse"
+	"github.com/josh5276/halp/shared"
+	"github.com/josh5276/halp/shared/atlassian"
+	"github.com/sirupsen/logrus"
+
+	"github.com/josh5276/halp/core"
+)
+
+type (
+	// editArgs holds the fields edit changes, the ones left empty are kept.
+	editArgs struct {
+		id          *core.Args
+		issue       *string
+		duration    *string
+		date        *string
+		start       *string
+		description *string
+	}
+
+	// deleteArgs holds the worklog delete removes.
+	deleteArgs struct {
+		id  *core.Args
+		yes *bool
+	}
+)
+
+var (
+	edits    editArgs
+	deletion deleteArgs
+)
+
+// editPlugin changes the fields of a worklog given on the command line.
+func editPlugin(p *argparse.Command) core.Plugin {
+	edits.id = core.Positional("ID")
+	cmd := p.NewCommand("edit", "Change a worklog: edit ID, with the ID shown by worklog list.")
+	edits.issue = cmd.String("", "issue", &argparse.Options{Help: "Move the worklog to this issue"})
+	edits.duration = cmd.String("", "duration", &argparse.Options{Help: "Time spent, e.g. 1h30m, 1.5h, 90m or 1:30"})
+	edits.date = cmd.String("", "date", &argparse.Options{
+		Help: fmt.Sprintf("Day the work was done, %s", shared.DateLayout),
+	})
+	edits.start = cmd.String("", "start", &argparse.Options{Help: "Time the work started, HH:MM"})
+	edits.description = cmd.String("", "description", &argparse.Options{Help: "Description of the work"})
+	return core.Plugin{CMD: cmd, Func: editFunc, Args: edits.id}
+}
+
+// deletePlugin removes a worklog, after asking for confirmation.
+func deletePlugin(p *argparse.Command) core.Plugin {
+	deletion.id = core.Positional("ID")
+	cmd := p.NewCommand("delete", "Delete a worklog: delete ID, with the ID shown by worklog list.")
+	deletion.yes = cmd.Flag("y", "yes", &argparse.Options{Help: "Delete the worklog without asking for confirmation"})
+	return core.Plugin{CMD: cmd, Func: deleteFunc, Args: deletion.id}
+}
+
+// editFunc function is executed from the caller
+func editFunc(ctx context.Context, env core.Env) error {
+	id, err := worklogID(edits.id)
+	if err != nil {
+		return core.WithCode(core.ExitUsage, err)
+	}
+	if *edits.issue == "" && *edits.duration == "" && *edits.date == "" && *edits.start == "" && *edits.description == "" {
+		return core.Errorf(core.ExitUsage, "jira.worklog.edit:nothing to change, pass --issue, --duration, --date, --start or --description")
+	}
+
+	atl, err := env.Atlassian()
+	if err != nil {
+		return core.WithCode(core.ExitConfig, err)
+	}
+	worklog, err := atl.Worklog(ctx, id)
+	if err != nil {
+		return core.WithCode(core.ExitAPI, err)
+	}
+	req, err := edits.apply(worklog.Request())
+	if err != nil {
+		return core.WithCode(core.ExitUsage, err)
+	}
+	if worklog, err = atl.UpdateWorklog(ctx, id, req); err != nil {
+		return core.WithCode(core.ExitAPI, err)
+	}
+	logrus.Infof("Updated worklog %d: %s to %s on %s.", worklog.TempoWorklogID,
+		shared.FormatMinutes(worklog.TimeSpentSeconds/60), worklog.Issue.Key, worklog.StartDate)
+	return nil
+}
+
+// apply changes the fields of the request given on the command line.
+func (e editArgs) apply(req atlassian.WorklogRequest) (atlassian.WorklogRequest, error) {
+	if *e.issue != "" {
+		req.IssueKey = strings.ToUpper(strings.TrimSpace(*e.issue))
+	}
+	if *e.duration != "" {
+		spent, err := shared.ParseDuration(*e.duration)
+		if err != nil {
+			return req, err
+		}
+		req.TimeSpentSeconds = int(spent / time.Second)
+		// Time billed in full is left to Tempo to bill in full again, non-billable
+		// or partly billed time keeps its billable time, within the new duration.
+		if req.BillableSeconds != nil && *req.BillableSeconds > req.TimeSpentSeconds {
+			billable := req.TimeSpentSeconds
+			req.BillableSeconds = &billable
+		}
+	}
+	if *e.date != "" {
+		day, err := time.Parse(shared.DateLayout, *e.date)
+		if err != nil {
+			return req, fmt.Errorf("jira.worklog.edit:--date %q is not a %s date", *e.date, shared.DateLayout)
+		}
+		req.StartDate = day.Format(shared.DateLayout)
+	}
+	if *e.start != "" {
+		at, err := time.Parse("15:04", *e.start)
+		if err != nil {
+			return req, fmt.Errorf("jira.worklog.edit:--start %q is not a HH:MM time", *e.start)
+		}
+		req.StartTime = at.Format("15:04:05")
+	}
+	if *e.description != "" {
+		req.Description = *e.description
+	}
+	return req, nil
+}
+
+// deleteFunc function is executed from the caller
+func deleteFunc(ctx context.Context, env core.Env) error {
+	id, err := worklogID(deletion.id)
+	if err != nil {
+		return core.WithCode(core.ExitUsage, err)
+	}
+	atl, err := env.Atlassian()
+	if err != nil {
+		return core.WithCode(core.ExitConfig, err)
+	}
+	worklog, err := atl.Worklog(ctx, id)
+	if err != nil {
+		return core.WithCode(core.ExitAPI, err)
+	}
+
+	if !*deletion.yes {
+		fmt.Fprintf(env.Stdout, "Worklog:     %d\n", worklog.TempoWorklogID)
+		fmt.Fprintf(env.Stdout, "Issue:       %s\n", worklog.Issue.Key)
+		fmt.Fprintf(env.Stdout, "Time spent:  %s\n", shared.FormatMinutes(worklog.TimeSpentSeconds/60))
+		fmt.Fprintf(env.Stdout, "Date:        %s %s\n", worklog.StartDate, strings.TrimSuffix(worklog.StartTime, ":00"))
+		fmt.Fprintf(env.Stdout, "Description: %s\n", worklog.Description)
+		ok, err := shared.ConfirmPrompt(env.Stdin, env.Stdout, "Delete this worklog?")
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return core.Errorf(core.ExitUsage, "jira.worklog.delete:no confirmation given, use --yes to delete without one")
+		} else if err != nil {
+			return core.WithCode(core.ExitError, err)
+		}
+		if !ok {
+			logrus.Info("Nothing was deleted.")
+			return nil
+		}
+	}
+
+	if err := atl.DeleteWorklog(ctx, id); err != nil {
+		return core.WithCode(core.ExitAPI, err)
+	}
+	logrus.Infof("Deleted worklog %d.", id)
+	return nil
+}
+
+// worklogID parses the Tempo worklog ID given as the argument of edit and delete.
+func worklogID(args *core.Args) (int, error) {
+	id, err := strconv.Atoi(args.Get(0))
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("jira.worklog:%q is not a worklog ID, see halp jira worklog list", args.Get(0))
+	}
+	return id, nil
+}
@@ -0,0 +1,108 @@
+package worklog
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/josh5276/halp/core"
+	"github.com/josh5276/halp/shared/atlassian"
+	"github.com/josh5276/halp/shared/atlassian/fake"
+)
+
+func TestPlugin_worklogList(t *testing.T) {
+	srv := fake.Start(t, fake.SharedFixture())
+	defer srv.Close()
+
+	// A teammate's worklog is left out of ours.
+	atl, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := atl.NewWorklog(context.Background(), atlassian.WorklogRequest{IssueKey: "NTC-1", TimeSpentSeconds: 3600,
+		StartDate: "2020-10-05", Description: "Reviewed the automation", AuthorAccountID: "def456"}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := testRun(t, srv, "", "", "list", "--from", "2020-10-01", "--to", "2020-10-31", "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Rows   []map[string]interface{}
+		Footer map[string]interface{}
+	}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("ERROR: decoding %s:%s", out, err)
+	}
+	ids := make([]float64, 0, len(doc.Rows))
+	for _, row := range doc.Rows {
+		ids = append(ids, row["id"].(float64))
+	}
+	if len(ids) != 4 || ids[0] != 101 || ids[1] != 102 || ids[3] != 104 || doc.Footer["minutes"] != 300.0 {
+		t.Fatalf("ERROR: unexpected list %s", out)
+	}
+	t.Logf("SUCCESS: listed worklogs %v", ids)
+}
+
+func TestPlugin_worklogEdit(t *testing.T) {
+	srv := fake.Start(t, fake.SharedFixture())
+	defer srv.Close()
+
+	_, err := testRun(t, srv, "", "", "edit", "102", "--duration", "45m", "--description", "Longer standup", "--start", "13:15")
+	if err != nil {
+		t.Fatal(err)
+	}
+	atl, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	worklog, err := atl.Worklog(context.Background(), 102)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if worklog.TimeSpentSeconds != 2700 || worklog.BillableSeconds != 0 || worklog.Description != "Longer standup" ||
+		worklog.StartTime != "13:15:00" || worklog.StartDate != "2020-10-01" || worklog.Issue.Key != "NTC-2" {
+		t.Fatalf("ERROR: unexpected edit %+v", worklog)
+	}
+
+	for _, args := range [][]string{{"edit", "102"}, {"edit", "abc", "--duration", "1h"}, {"edit", "102", "--date", "tomorrow"}} {
+		if _, err := testRun(t, srv, "", "", args...); core.Code(err) != core.ExitUsage {
+			t.Errorf("ERROR: %v: expected a usage error, got %v", args, err)
+		}
+	}
+	if _, err := testRun(t, srv, "", "", "edit", "999", "--duration", "1h"); core.Code(err) != core.ExitAPI {
+		t.Errorf("ERROR: expected an API error for an unknown worklog, got %v", err)
+	}
+	t.Logf("SUCCESS: edited %+v", worklog)
+}
+
+func TestPlugin_worklogDelete(t *testing.T) {
+	srv := fake.Start(t, fake.SharedFixture())
+	defer srv.Close()
+
+	atl, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exists := func(id int) bool {
+		_, err := atl.Worklog(context.Background(), id)
+		return err == nil
+	}
+
+	out, err := testRun(t, srv, "", "no\n", "delete", "103")
+	if err != nil || !exists(103) || !strings.Contains(out, "Delete this worklog? [y/n]") {
+		t.Fatalf("ERROR: expected 103 to be kept when declined, got %v\n%s", err, out)
+	}
+	if _, err := testRun(t, srv, "", "", "delete", "103"); core.Code(err) != core.ExitUsage || !exists(103) {
+		t.Fatalf("ERROR: expected a usage error without a confirmation, got %v", err)
+	}
+	if _, err := testRun(t, srv, "", "yes\n", "delete", "103"); err != nil || exists(103) {
+		t.Fatalf("ERROR: expected 103 to be deleted once confirmed, got %v", err)
+	}
+	if _, err := testRun(t, srv, "", "", "delete", "104", "--yes"); err != nil || exists(104) {
+		t.Fatalf("ERROR: expected --yes to delete 104 without asking, got %v", err)
+	}
+	t.Logf("SUCCESS: deleted worklogs 103 and 104")
+}
@@ -0,0 +1,100 @@
+package worklog
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/josh5276/halp/shared/atlassian"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultThreads is the number of concurrent issue searches when --threads
+// is not given.
+const defaultThreads = 10
+
+// issueClient is the part of the Atlassian client used to look up issues.
+type issueClient interface {
+	JiraIssues(ctx context.Context, issueKeys []string) (map[string]atlassian.JIRAIssue, error)
+}
+
+// fetchIssues looks up every distinct issue referenced by the worklogs in
+// batches of atlassian.IssueBatchSize keys, spread over a pool of up to
+// threads workers. Batches that fail to load are logged and their issues
+// left out of the result, the keys that could not be fetched are returned
+// sorted so the report can say what is missing.
+func fetchIssues(
+	ctx context.Context,
+	atl issueClient,
+	worklogs []atlassian.Worklog,
+	threads int,
+) (map[string]atlassian.JIRAIssue, []string, error) {
+	keys := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, item := range worklogs {
+		if !seen[item.Issue.Key] {
+			seen[item.Issue.Key] = true
+			keys = append(keys, item.Issue.Key)
+		}
+	}
+	if threads < 1 {
+		threads = 1
+	}
+	batches := make([][]string, 0)
+	for start := 0; start < len(keys); start += atlassian.IssueBatchSize {
+		end := start + atlassian.IssueBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		batches = append(batches, keys[start:end])
+	}
+	if threads > len(batches) {
+		threads = len(batches)
+	}
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		jobs   = make(chan []string)
+		issues = make(map[string]atlassian.JIRAIssue, len(keys))
+		failed = make([]string, 0)
+	)
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range jobs {
+				found, err := atl.JiraIssues(ctx, batch)
+				if err != nil && ctx.Err() == nil {
+					logrus.Errorf("Error fetching issues %s, %s", strings.Join(batch, ", "), err)
+				}
+				mu.Lock()
+				for key, issue := range found {
+					issues[key] = issue
+				}
+				if err != nil {
+					for _, key := range batch {
+						if _, ok := found[key]; !ok {
+							failed = append(failed, key)
+						}
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, batch := range batches {
+		select {
+		case jobs <- batch:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	sort.Strings(failed)
+	return issues, failed, ctx.Err()
+}
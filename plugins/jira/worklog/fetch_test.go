@@ -0,0 +1,95 @@
+package worklog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/josh5276/halp/shared/atlassian"
+)
+
+// testIssueClient records the searches made and how many ran at once.
+type testIssueClient struct {
+	mu      sync.Mutex
+	calls   map[string]int
+	batches int
+	running int
+	peak    int
+}
+
+func (c *testIssueClient) JiraIssues(_ context.Context, keys []string) (map[string]atlassian.JIRAIssue, error) {
+	c.mu.Lock()
+	c.batches++
+	c.running++
+	if c.running > c.peak {
+		c.peak = c.running
+	}
+	for _, key := range keys {
+		c.calls[key]++
+	}
+	c.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	c.mu.Lock()
+	c.running--
+	c.mu.Unlock()
+
+	issues := make(map[string]atlassian.JIRAIssue)
+	for _, key := range keys {
+		if key == "BAD-1" {
+			return issues, errors.New("400 Bad Request")
+		}
+		var issue atlassian.JIRAIssue
+		issue.Key = key
+		issues[key] = issue
+	}
+	return issues, nil
+}
+
+func Test_fetchIssues(t *testing.T) {
+	worklogs := make([]atlassian.Worklog, 0)
+	for i := 0; i < 3*atlassian.IssueBatchSize; i++ {
+		var w atlassian.Worklog
+		// Every issue shows up on two worklogs.
+		w.Issue.Key = fmt.Sprintf("A-%d", i/2)
+		worklogs = append(worklogs, w)
+	}
+
+	atl := &testIssueClient{calls: make(map[string]int)}
+	issues, failed, err := fetchIssues(context.Background(), atl, worklogs, 10)
+	if err != nil || len(failed) != 0 {
+		t.Fatalf("ERROR: expected every issue, %v missing: %v", failed, err)
+	}
+	expected := 3 * atlassian.IssueBatchSize / 2
+	if len(issues) != expected || len(atl.calls) != expected {
+		t.Fatalf("ERROR: fetched %d issues with %d lookups, expected %d", len(issues), len(atl.calls), expected)
+	}
+	for key, n := range atl.calls {
+		if n != 1 {
+			t.Errorf("ERROR: %s looked up %d times", key, n)
+		}
+	}
+	if atl.batches != 2 || atl.peak != 2 {
+		t.Errorf("ERROR: expected 2 concurrent searches, got %d with %d at once", atl.batches, atl.peak)
+	}
+
+	var bad atlassian.Worklog
+	bad.Issue.Key = "BAD-1"
+	if issues, failed, err = fetchIssues(context.Background(), atl, append(worklogs[:2:2], bad), 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || len(failed) != 1 || failed[0] != "BAD-1" {
+		t.Errorf("ERROR: expected the issues of a failed batch to be left out, got %d and %v missing", len(issues), failed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := fetchIssues(ctx, atl, worklogs, 3); err != context.Canceled {
+		t.Fatalf("ERROR: expected the cancellation to be returned, got %v", err)
+	}
+	t.Logf("SUCCESS: fetched %d issues in %d searches", expected, atl.batches)
+}
@@ -0,0 +1,185 @@
+package worklog
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/josh5276/halp/core"
+	"github.com/josh5276/halp/core/keyring"
+	"github.com/josh5276/halp/shared"
+	"github.com/josh5276/halp/shared/atlassian"
+)
+
+// filterSection is the settings.ini section the default filter rules are
+// read from, e.g.
+//
+//	[worklog.filter]
+//	summary       = [NTC] DELIVER
+//	summary_regex = ^\[(NTC|OPS)\]
+//	projects      = NTC, OPS
+//	statuses      = In Progress, Done
+//	attributes    = _Account_=ACME, _Account_=INTERNAL
+const filterSection = "worklog.filter"
+
+type (
+	// filter decides which worklogs make it into the report. A worklog is
+	// kept only when it passes every rule; the first rule it fails is
+	// charged with the exclusion so the report can say why it is missing.
+	// unfetched counts the worklogs skipped as their issue could not be fetched.
+	filter struct {
+		rules     []rule
+		excluded  []int
+		unfetched int
+	}
+
+	// rule is a single filter condition, name is how it is shown in the report.
+	rule struct {
+		name  string
+		match func(atlassian.Worklog, atlassian.JIRAIssue) bool
+	}
+
+	// filterArgs holds the command line overrides for the configured rules.
+	filterArgs struct {
+		projects     *[]string
+		summaryMatch *string
+		all          *bool
+	}
+)
+
+// newFilter builds the filter from the settings file, replacing any rule
+// that was overridden on the command line. --all disables every rule.
+func newFilter(cfg keyring.Settings, args filterArgs) (*filter, error) {
+	f := &filter{}
+	if *args.all {
+		return f, nil
+	}
+
+	summaries := cfg.Values(filterSection, "summary")
+	summaryRegex := cfg.Value(filterSection, "summary_regex")
+	if *args.summaryMatch != "" {
+		summaries, summaryRegex = nil, *args.summaryMatch
+	}
+	projects := cfg.Values(filterSection, "projects")
+	if len(*args.projects) > 0 {
+		projects = *args.projects
+	}
+
+	if len(summaries) > 0 {
+		f.add(fmt.Sprintf("summary contains %s", strings.Join(summaries, " or ")),
+			func(_ atlassian.Worklog, issue atlassian.JIRAIssue) bool {
+				for _, s := range summaries {
+					if shared.IContains(issue.Fields.Summary, s) {
+						return true
+					}
+				}
+				return false
+			})
+	}
+	if summaryRegex != "" {
+		re, err := regexp.Compile("(?i)" + summaryRegex)
+		if err != nil {
+			return nil, fmt.Errorf("summary match %q:%s", summaryRegex, err)
+		}
+		f.add(fmt.Sprintf("summary matches /%s/", summaryRegex),
+			func(_ atlassian.Worklog, issue atlassian.JIRAIssue) bool {
+				return re.MatchString(issue.Fields.Summary)
+			})
+	}
+	if len(projects) > 0 {
+		f.add(fmt.Sprintf("project in %s", strings.Join(projects, ", ")),
+			func(_ atlassian.Worklog, issue atlassian.JIRAIssue) bool {
+				return inFold(issue.Fields.Project.Key, projects)
+			})
+	}
+	if statuses := cfg.Values(filterSection, "statuses"); len(statuses) > 0 {
+		f.add(fmt.Sprintf("status in %s", strings.Join(statuses, ", ")),
+			func(_ atlassian.Worklog, issue atlassian.JIRAIssue) bool {
+				return inFold(issue.Fields.Status.Name, statuses)
+			})
+	}
+	return f, f.addAttributes(cfg.Values(filterSection, "attributes"))
+}
+
+// addAttributes adds one rule per attribute key. Values given for the same
+// key are alternatives, e.g. _Account_=A, _Account_=B keeps either account.
+func (f *filter) addAttributes(pairs []string) error {
+	keys := make([]string, 0)
+	values := make(map[string][]string)
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return fmt.Errorf("attributes: expected key=value, got %q", pair)
+		}
+		key := strings.TrimSpace(kv[0])
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		values[key] = append(values[key], strings.TrimSpace(kv[1]))
+	}
+	for _, key := range keys {
+		key, allowed := key, values[key]
+		f.add(fmt.Sprintf("attribute %s in %s", key, strings.Join(allowed, ", ")),
+			func(w atlassian.Worklog, _ atlassian.JIRAIssue) bool {
+				return inFold(attribute(w, key), allowed)
+			})
+	}
+	return nil
+}
+
+func (f *filter) add(name string, match func(atlassian.Worklog, atlassian.JIRAIssue) bool) {
+	f.rules = append(f.rules, rule{name: name, match: match})
+	f.excluded = append(f.excluded, 0)
+}
+
+// keep reports whether the worklog passes every rule, counting it against
+// the first rule it fails.
+func (f *filter) keep(w atlassian.Worklog, issue atlassian.JIRAIssue) bool {
+	for i, r := range f.rules {
+		if !r.match(w, issue) {
+			f.excluded[i]++
+			return false
+		}
+	}
+	return true
+}
+
+// notes describes how many worklogs each rule excluded, for the report.
+func (f *filter) notes() []string {
+	notes := make([]string, 0, len(f.rules))
+	for i, r := range f.rules {
+		notes = append(notes, fmt.Sprintf("%d worklogs excluded by: %s", f.excluded[i], r.name))
+	}
+	if f.unfetched > 0 {
+		notes = append(notes, fmt.Sprintf("%d worklogs skipped, their issues could not be fetched", f.unfetched))
+	}
+	return notes
+}
+
+// incomplete fails the command after the report when worklogs were skipped and the
+// report is not a table, so a script does not take it for the whole period.
+func (f *filter) incomplete(format shared.Format) error {
+	if f.unfetched == 0 || format == shared.FormatTable {
+		return nil
+	}
+	return core.Errorf(core.ExitAPI, "jira.worklog:%d worklogs skipped, their issues could not be fetched", f.unfetched)
+}
+
+// attribute returns the value of a Tempo work attribute on the worklog.
+func attribute(w atlassian.Worklog, key string) string {
+	for _, attr := range w.Attributes.Values {
+		if strings.EqualFold(attr.Key, key) {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func inFold(s string, list []string) bool {
+	for _, v := range list {
+		if strings.EqualFold(s, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package worklog
+
+import (
+	"testing"
+
+	"github.com/josh5276/halp/shared/atlassian"
+	"github.com/josh5276/halp/shared/atlassian/fake"
+)
+
+const testFilterCfg = `
+[worklog.filter]
+summary    = [NTC] DELIVER
+projects   = NTC, OPS
+attributes = _Account_=ACME, _Account_=INTERNAL
+`
+
+func testWorklog(key, project, summary, account string) (atlassian.Worklog, atlassian.JIRAIssue) {
+	var (
+		w     atlassian.Worklog
+		issue atlassian.JIRAIssue
+	)
+	w.Issue.Key = key
+	w.Attributes.Values = []atlassian.WorklogAttribute{{Key: "_Account_", Value: account}}
+	issue.Key = key
+	issue.Fields.Project.Key = project
+	issue.Fields.Summary = summary
+	return w, issue
+}
+
+func Test_filter(t *testing.T) {
+	var (
+		none    []string
+		empty   string
+		all     bool
+		mine    = []string{"MINE"}
+		deliver = "deliver (the|another)"
+	)
+	var tests = []struct {
+		name     string
+		args     filterArgs
+		kept     int
+		excluded []int
+	}{
+		{"configured", filterArgs{&none, &empty, &all}, 1, []int{1, 2, 1}},
+		{"project override", filterArgs{&mine, &empty, &all}, 1, []int{1, 3, 0}},
+		{"summary override", filterArgs{&none, &deliver, &all}, 1, []int{3, 0, 1}},
+	}
+	for _, tc := range tests {
+		f, err := newFilter(fake.Settings(t, testFilterCfg), tc.args)
+		if err != nil {
+			t.Fatalf("ERROR: %s:newFilter:%s", tc.name, err)
+		}
+		kept := 0
+		for _, item := range [][4]string{
+			{"NTC-1", "NTC", "[NTC] Deliver the thing", "ACME"},
+			{"NTC-2", "NTC", "[NTC] Deliver another", "OTHER"},
+			{"ABC-1", "ABC", "[NTC] Deliver elsewhere", "ACME"},
+			{"MINE-1", "MINE", "internal meeting", "ACME"},
+			{"MINE-2", "MINE", "[NTC] DELIVER deploy", "ACME"},
+		} {
+			if f.keep(testWorklog(item[0], item[1], item[2], item[3])) {
+				kept++
+			}
+		}
+		if kept != tc.kept {
+			t.Errorf("ERROR: %s kept %d worklogs, expected %d (%v)", tc.name, kept, tc.kept, f.notes())
+		}
+		for i, n := range tc.excluded {
+			if f.excluded[i] != n {
+				t.Errorf("ERROR: %s rule %q excluded %d, expected %d", tc.name, f.rules[i].name, f.excluded[i], n)
+			}
+		}
+	}
+
+	all = true
+	f, err := newFilter(fake.Settings(t, testFilterCfg), filterArgs{&none, &empty, &all})
+	if err != nil || len(f.rules) != 0 {
+		t.Fatalf("ERROR: --all should disable every rule, got %v:%v", f.notes(), err)
+	}
+	t.Logf("SUCCESS: filtered worklogs for %d configurations", len(tests))
+}
@@ -0,0 +1,233 @@
+package worklog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jokelyo/argparse"
+	"github.com/josh5276/halp/core/keyring"
+	"github.com/josh5276/halp/shared"
+
+	"github.com/josh5276/halp/core"
+)
+
+// defaultDailyTarget is the time expected to be logged on a working day.
+const defaultDailyTarget = 8 * time.Hour
+
+type (
+	// schedule is the time expected to be logged each day, read from the
+	// [worklog] section, e.g.
+	//
+	//	[worklog]
+	//	daily_target = 7h30m
+	//	working_days = Mon-Thu
+	//	holidays     = 2020-11-26, 2020-12-25
+	schedule struct {
+		target   int
+		workdays map[time.Weekday]bool
+		holidays map[string]bool
+	}
+
+	// day is the time logged on a day against its target, in minutes.
+	day struct {
+		target   int
+		logged   int
+		billable int
+	}
+)
+
+// gapsPlugin compares the time logged each day with the daily target.
+func gapsPlugin(p *argparse.Command) core.Plugin {
+	cmd := p.NewCommand("gaps", "List the days logged under or over the daily target, with the utilization "+
+		"and billable time. Takes the same range as worklog.")
+	return core.Plugin{CMD: cmd, Func: gapsFunc}
+}
+
+// gapsFunc function is executed from the caller
+func gapsFunc(ctx context.Context, env core.Env) error {
+	loc, err := env.Settings.Location()
+	if err != nil {
+		return core.WithCode(core.ExitConfig, err)
+	}
+	period, err := dates.Range(time.Now(), loc)
+	if err != nil {
+		return core.WithCode(core.ExitUsage, err)
+	}
+	sched, err := newSchedule(env.Settings)
+	if err != nil {
+		return core.WithCode(core.ExitConfig, err)
+	}
+
+	// Month to date is reported along with the range, so fetch both.
+	month := shared.DateRange{
+		From: time.Date(period.To.Year(), period.To.Month(), 1, 0, 0, 0, 0, loc),
+		To:   period.To,
+	}
+	from := period.From
+	if month.From.Before(from) {
+		from = month.From
+	}
+	atl, err := env.Atlassian()
+	if err != nil {
+		return core.WithCode(core.ExitConfig, err)
+	}
+	accountID, err := atl.AccountID(ctx)
+	if err != nil {
+		return core.WithCode(core.ExitAPI, err)
+	}
+	worklogs, err := atl.WorkLogs(ctx, period.To.Format(shared.DateLayout), from.Format(shared.DateLayout))
+	if err != nil {
+		return core.WithCode(core.ExitAPI, err)
+	}
+	days := make(map[string]*day)
+	for _, d := range (shared.DateRange{From: from, To: period.To}).Days() {
+		days[d.Format(shared.DateLayout)] = &day{target: sched.minutes(d)}
+	}
+	for _, worklog := range ownWorklogs(worklogs, accountID) {
+		if d, ok := days[worklog.StartDate]; ok {
+			d.logged += worklog.TimeSpentSeconds / 60
+			d.billable += worklog.BillableSeconds / 60
+		}
+	}
+
+	r := shared.Report{
+		Title: fmt.Sprintf("Worklog gaps %s", period),
+		Columns: []shared.Column{
+			{Name: "DATE"},
+			{Name: "DAY"},
+			{Name: "TARGET", Key: "target_minutes", Format: shared.FormatMinutes},
+			{Name: "LOGGED", Key: "logged_minutes", Format: shared.FormatMinutes},
+			{Name: "DIFFERENCE", Key: "difference_minutes", Format: formatDifference},
+			{Name: "BILLABLE", Key: "billable_minutes", Format: shared.FormatMinutes},
+			{Name: "STATUS"},
+		},
+	}
+	var total day
+	for _, d := range period.Days() {
+		logged := days[d.Format(shared.DateLayout)]
+		total.add(*logged)
+		status := "under"
+		switch {
+		case logged.logged == logged.target:
+			continue
+		case logged.logged > logged.target:
+			status = "over"
+		}
+		r.Rows = append(r.Rows, []interface{}{d.Format(shared.DateLayout), d.Format("Mon"), logged.target,
+			logged.logged, logged.logged - logged.target, logged.billable, status})
+	}
+	r.Footer = []interface{}{"Total", nil, total.target, total.logged, total.logged - total.target, total.billable, nil}
+
+	r.Notes = append(r.Notes, total.summary(period.String()))
+	if !month.From.Equal(period.From) {
+		var mtd day
+		for _, d := range month.Days() {
+			mtd.add(*days[d.Format(shared.DateLayout)])
+		}
+		r.Notes = append(r.Notes, mtd.summary("Month to date"))
+	}
+	return shared.Render(env.Stdout, env.Output, r)
+}
+
+// newSchedule reads the daily target, working days and holidays from the settings.
+func newSchedule(cfg keyring.Settings) (schedule, error) {
+	s := schedule{
+		target:   int(defaultDailyTarget / time.Minute),
+		workdays: make(map[time.Weekday]bool),
+		holidays: make(map[string]bool),
+	}
+	if v := cfg.Value(section, "daily_target"); v != "" {
+		target, err := shared.ParseDuration(v)
+		if err != nil {
+			return s, fmt.Errorf("worklog.daily_target:%s", err)
+		}
+		s.target = int(target / time.Minute)
+	}
+
+	workdays := cfg.Values(section, "working_days")
+	if len(workdays) == 0 {
+		workdays = []string{"Mon-Fri"}
+	}
+	for _, v := range workdays {
+		bounds := strings.SplitN(v, "-", 2)
+		first, ok := weekday(bounds[0])
+		last := first
+		if len(bounds) == 2 {
+			var lastOK bool
+			last, lastOK = weekday(bounds[1])
+			ok = ok && lastOK
+		}
+		if !ok {
+			return s, fmt.Errorf("worklog.working_days:%q is not a day such as Mon or a range such as Mon-Fri", v)
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			s.workdays[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+
+	for _, v := range cfg.Values(section, "holidays") {
+		holiday, err := time.Parse(shared.DateLayout, v)
+		if err != nil {
+			return s, fmt.Errorf("worklog.holidays:%q is not a %s date", v, shared.DateLayout)
+		}
+		s.holidays[holiday.Format(shared.DateLayout)] = true
+	}
+	return s, nil
+}
+
+// weekday parses the name of a day of the week, e.g. Mon or monday.
+func weekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if len(name) >= 3 && strings.HasPrefix(strings.ToLower(d.String()), name) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// minutes is the target of the day, nothing on days off and holidays.
+func (s schedule) minutes(d time.Time) int {
+	if !s.workdays[d.Weekday()] || s.holidays[d.Format(shared.DateLayout)] {
+		return 0
+	}
+	return s.target
+}
+
+func (d *day) add(other day) {
+	d.target += other.target
+	d.logged += other.logged
+	d.billable += other.billable
+}
+
+// summary describes the utilization and the billable share of the time logged.
+func (d day) summary(label string) string {
+	utilization := "no target"
+	if d.target > 0 {
+		utilization = fmt.Sprintf("%d%% utilization", d.logged*100/d.target)
+	}
+	billable := ""
+	if d.logged > 0 {
+		billable = fmt.Sprintf(" (%d%%)", d.billable*100/d.logged)
+	}
+	return fmt.Sprintf("%s: logged %s of %s, %s; billable %s%s, non-billable %s", label,
+		shared.FormatMinutes(d.logged), shared.FormatMinutes(d.target), utilization,
+		shared.FormatMinutes(d.billable), billable, shared.FormatMinutes(d.logged-d.billable))
+}
+
+// formatDifference shows a number of minutes with its sign, e.g. "-1h 30m".
+func formatDifference(v interface{}) string {
+	minutes, ok := v.(int)
+	if !ok {
+		return fmt.Sprint(v)
+	}
+	if minutes < 0 {
+		return "-" + shared.FormatMinutes(-minutes)
+	}
+	return "+" + shared.FormatMinutes(minutes)
+}
@@ -0,0 +1,99 @@
+package worklog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/josh5276/halp/shared/atlassian"
+	"github.com/josh5276/halp/shared/atlassian/fake"
+)
+
+func TestNewSchedule(t *testing.T) {
+	var tests = []struct {
+		cfg  string
+		want string
+	}{
+		{"", "Mon Tue Wed Thu Fri"},
+		{"[worklog]\nworking_days = Tue, thursday\n", "Tue Thu"},
+		{"[worklog]\nworking_days = Fri-Mon\n", "Sun Mon Fri Sat"},
+		{"[worklog]\nworking_days = Funday\n", ""},
+		{"[worklog]\ndaily_target = 8\n", ""},
+		{"[worklog]\nholidays = 12/25/2020\n", ""},
+	}
+	for _, test := range tests {
+		s, err := newSchedule(fake.Settings(t, test.cfg))
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("ERROR: expected %q to fail", test.cfg)
+			}
+			continue
+		} else if err != nil {
+			t.Fatalf("ERROR: %q:%s", test.cfg, err)
+		}
+		days := make([]string, 0)
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if s.workdays[d] {
+				days = append(days, d.String()[:3])
+			}
+		}
+		if strings.Join(days, " ") != test.want {
+			t.Errorf("ERROR: %q gave %v, expected %s", test.cfg, days, test.want)
+		}
+	}
+	t.Logf("SUCCESS: parsed %d schedules", len(tests))
+}
+
+func TestPlugin_worklogGaps(t *testing.T) {
+	srv := fake.Start(t, fake.SharedFixture())
+	defer srv.Close()
+
+	// A teammate's time on the same days doesn't fill our gaps.
+	atl, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := atl.NewWorklog(context.Background(), atlassian.WorklogRequest{IssueKey: "OPS-7", TimeSpentSeconds: 10800,
+		StartDate: "2020-10-05", Description: "Handed the BGP flap over", AuthorAccountID: "def456"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := "[worklog]\ndaily_target = 2h\nholidays = 2020-10-02\n"
+	out, err := testRun(t, srv, cfg, "", "gaps", "--from", "2020-10-01", "--to", "2020-10-06", "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Rows   []map[string]interface{}
+		Footer map[string]interface{}
+		Notes  []string
+	}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("ERROR: decoding %s:%s", out, err)
+	}
+	expected := []string{"2020-10-01 over 30", "2020-10-02 over 90", "2020-10-05 under -60", "2020-10-06 under -120"}
+	if len(doc.Rows) != len(expected) {
+		t.Fatalf("ERROR: unexpected gaps %s", out)
+	}
+	for i, row := range doc.Rows {
+		if got := fmt.Sprintf("%s %s %v", row["date"], row["status"], row["difference_minutes"]); got != expected[i] {
+			t.Errorf("ERROR: row %d is %s, expected %s", i, got, expected[i])
+		}
+	}
+	if doc.Footer["target_minutes"] != 360.0 || doc.Footer["logged_minutes"] != 300.0 || doc.Footer["billable_minutes"] != 270.0 {
+		t.Fatalf("ERROR: unexpected totals %v", doc.Footer)
+	}
+	if len(doc.Notes) != 1 || !strings.Contains(doc.Notes[0], "83% utilization") || !strings.Contains(doc.Notes[0], "non-billable 0h 30m") {
+		t.Fatalf("ERROR: unexpected notes %v", doc.Notes)
+	}
+
+	// A range starting mid month also reports the month to date.
+	out, err = testRun(t, srv, cfg, "", "gaps", "--from", "2020-10-05", "--to", "2020-10-06")
+	if err != nil || !strings.Contains(out, "Month to date: logged 5h 0m of 6h 0m") {
+		t.Fatalf("ERROR: expected the month to date, got %v\n%s", err, out)
+	}
+	t.Logf("SUCCESS: gaps\n%s", out)
+}
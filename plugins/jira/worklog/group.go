@@ -0,0 +1,282 @@
+package worklog
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/josh5276/halp/shared"
+	"github.com/josh5276/halp/shared/atlassian"
+)
+
+// defaultAccountAttribute is the Tempo work attribute holding the account,
+// override it with account_attribute in the [worklog] section.
+const defaultAccountAttribute = "_Account_"
+
+type (
+	// entry is a single worklog that passed the filters, joined with its issue.
+	// rate is the hourly rate of the issue's project, 0 without rates.
+	entry struct {
+		worklog atlassian.Worklog
+		issue   atlassian.JIRAIssue
+		rate    float64
+	}
+
+	// totals is the time aggregated for a group of worklogs, logged and
+	// billable, along with the cost of the billable time.
+	totals struct {
+		seconds  int
+		billable int
+		cost     float64
+	}
+
+	// dimension is a field the report can be grouped by. value returns the
+	// group key for an entry, and desc an optional description of the key
+	// shown next to it when the dimension is the last level.
+	dimension struct {
+		column string
+		value  func(e entry) string
+		desc   func(e entry) string
+	}
+
+	// group is a node in the grouping tree, one level per dimension.
+	group struct {
+		key      string
+		desc     string
+		totals   totals
+		children map[string]*group
+	}
+)
+
+// dimensions returns the dimensions --group-by accepts, keyed by name.
+func dimensions(accountAttribute string) map[string]dimension {
+	return map[string]dimension{
+		"issue": {
+			column: "JIRA ID",
+			value:  func(e entry) string { return e.worklog.Issue.Key },
+			desc:   func(e entry) string { return e.issue.Fields.Project.Name },
+		},
+		"project": {
+			column: "PROJECT",
+			value:  func(e entry) string { return e.issue.Fields.Project.Key },
+			desc:   func(e entry) string { return e.issue.Fields.Project.Name },
+		},
+		"day": {
+			column: "DAY",
+			value:  func(e entry) string { return e.worklog.StartDate },
+			desc: func(e entry) string {
+				if day, err := time.Parse(shared.DateLayout, e.worklog.StartDate); err == nil {
+					return day.Weekday().String()
+				}
+				return ""
+			},
+		},
+		"week": {
+			column: "WEEK",
+			value: func(e entry) string {
+				day, err := time.Parse(shared.DateLayout, e.worklog.StartDate)
+				if err != nil {
+					return e.worklog.StartDate
+				}
+				year, week := day.ISOWeek()
+				return fmt.Sprintf("%d-W%02d", year, week)
+			},
+		},
+		"account": {
+			column: "ACCOUNT",
+			value:  func(e entry) string { return attribute(e.worklog, accountAttribute) },
+		},
+	}
+}
+
+// parseGroupBy resolves the comma separated --group-by levels, defaulting
+// to grouping by issue.
+func parseGroupBy(groupBy, accountAttribute string) ([]dimension, []string, error) {
+	known := dimensions(accountAttribute)
+	levels := make([]dimension, 0)
+	names := make([]string, 0)
+	for _, name := range strings.Split(groupBy, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		dim, ok := known[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("--group-by: unknown level %q, use one of issue, project, day, week, account", name)
+		}
+		levels, names = append(levels, dim), append(names, name)
+	}
+	if len(levels) == 0 {
+		levels, names = []dimension{known["issue"]}, []string{"issue"}
+	}
+	return levels, names, nil
+}
+
+func (t *totals) add(e entry) {
+	t.seconds += e.worklog.TimeSpentSeconds
+	t.billable += e.worklog.BillableSeconds
+	t.cost += float64(e.worklog.BillableSeconds) / 3600 * e.rate
+}
+
+func (t totals) minutes() int {
+	return t.seconds / 60
+}
+
+// values are the logged, billable and non-billable minutes, followed by the
+// cost when the report has one, in the order of valueColumns.
+func (t totals) values(costs *rates) []interface{} {
+	values := []interface{}{t.minutes(), t.billable / 60, t.minutes() - t.billable/60}
+	if costs != nil {
+		values = append(values, math.Round(t.cost*100)/100)
+	}
+	return values
+}
+
+// valueColumns are the columns of the aggregated time, see totals.values.
+func valueColumns(costs *rates) []shared.Column {
+	columns := []shared.Column{
+		{Name: "HOURS SPENT", Key: "minutes", Format: shared.FormatMinutes},
+		{Name: "BILLABLE", Key: "billable_minutes", Format: shared.FormatMinutes},
+		{Name: "NON-BILLABLE", Key: "non_billable_minutes", Format: shared.FormatMinutes},
+	}
+	if costs != nil {
+		columns = append(columns, shared.Column{Name: costs.column(), Key: "cost", Format: formatCost})
+	}
+	return columns
+}
+
+// groupEntries builds the grouping tree, aggregating the totals at every level.
+func groupEntries(entries []entry, levels []dimension) *group {
+	root := &group{children: make(map[string]*group)}
+	for _, e := range entries {
+		node := root
+		node.totals.add(e)
+		for _, dim := range levels {
+			key := dim.value(e)
+			child, ok := node.children[key]
+			if !ok {
+				child = &group{key: key, children: make(map[string]*group)}
+				if dim.desc != nil {
+					child.desc = dim.desc(e)
+				}
+				node.children[key] = child
+			}
+			child.totals.add(e)
+			node = child
+		}
+	}
+	return root
+}
+
+// sorted returns the children of the group ordered by key.
+func (g *group) sorted() []*group {
+	children := make([]*group, 0, len(g.children))
+	for _, child := range g.children {
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].key < children[j].key })
+	return children
+}
+
+// groupedReport renders the grouping tree as one row per leaf group, with a
+// subtotal row closing every group above the last level.
+func groupedReport(title string, entries []entry, levels []dimension, names []string, costs *rates) shared.Report {
+	last := levels[len(levels)-1]
+	r := shared.Report{Title: title}
+	for _, dim := range levels {
+		r.Columns = append(r.Columns, shared.Column{Name: dim.column})
+	}
+	if last.desc != nil {
+		r.Columns = append(r.Columns, shared.Column{Name: "DESCRIPTION"})
+	}
+	// The time columns start after the levels and their description.
+	first := len(r.Columns)
+	r.Columns = append(r.Columns, valueColumns(costs)...)
+	r.Columns = append(r.Columns, shared.Column{Name: "SUBTOTAL", Hidden: true})
+
+	// row lays out a row of the report. Subtotal rows fill the keys of the
+	// levels above them and mark the first empty level for the table.
+	row := func(keys []interface{}, desc string, t totals, subtotal string) []interface{} {
+		row := make([]interface{}, len(r.Columns))
+		copy(row, keys)
+		if subtotal != "" {
+			row[len(keys)] = shared.Label("Subtotal")
+			row[len(row)-1] = subtotal
+		} else if desc != "" {
+			row[first-1] = desc
+		}
+		copy(row[first:], t.values(costs))
+		return row
+	}
+
+	root := groupEntries(entries, levels)
+	var walk func(g *group, depth int, prefix []interface{})
+	walk = func(g *group, depth int, prefix []interface{}) {
+		for _, child := range g.sorted() {
+			keys := append(prefix[:len(prefix):len(prefix)], child.key)
+			if depth == len(levels)-1 {
+				r.Rows = append(r.Rows, row(keys, child.desc, child.totals, ""))
+				continue
+			}
+			walk(child, depth+1, keys)
+			r.Rows = append(r.Rows, row(keys, "", child.totals, names[depth]))
+		}
+	}
+	walk(root, 0, nil)
+
+	r.Footer = make([]interface{}, len(r.Columns))
+	r.Footer[first-1] = "Total"
+	copy(r.Footer[first:], root.totals.values(costs))
+	return r
+}
+
+// pivotReport renders a grid with one row per group and one column per day
+// of the period, e.g. issues down the side and days along the top.
+func pivotReport(title string, period shared.DateRange, entries []entry, level dimension, costs *rates) shared.Report {
+	days := period.Days()
+	r := shared.Report{Title: title, Columns: []shared.Column{{Name: level.column}}}
+	index := make(map[string]int, len(days))
+	for i, day := range days {
+		key := day.Format(shared.DateLayout)
+		index[key] = i + 1
+		r.Columns = append(r.Columns, shared.Column{Name: day.Format("01-02"), Key: key, Format: shared.FormatMinutes})
+	}
+	// The time columns total each row, the first one named after the grid.
+	first := len(r.Columns)
+	r.Columns = append(r.Columns, valueColumns(costs)...)
+	r.Columns[first].Name = "TOTAL"
+
+	root := groupEntries(entries, []dimension{level})
+	daily := make([]totals, len(days))
+	for _, child := range root.sorted() {
+		row := make([]interface{}, len(r.Columns))
+		row[0] = child.key
+		cells := make([]totals, len(days))
+		for _, e := range entries {
+			if level.value(e) != child.key {
+				continue
+			}
+			if i, ok := index[e.worklog.StartDate]; ok {
+				cells[i-1].add(e)
+				daily[i-1].add(e)
+			}
+		}
+		for i, cell := range cells {
+			if cell.seconds > 0 {
+				row[i+1] = cell.minutes()
+			}
+		}
+		copy(row[first:], child.totals.values(costs))
+		r.Rows = append(r.Rows, row)
+	}
+
+	r.Footer = make([]interface{}, len(r.Columns))
+	r.Footer[0] = "Total"
+	for i, day := range daily {
+		r.Footer[i+1] = day.minutes()
+	}
+	copy(r.Footer[first:], root.totals.values(costs))
+	return r
+}
@@ -0,0 +1,118 @@
+package worklog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/josh5276/halp/shared"
+	"github.com/josh5276/halp/shared/atlassian/fake"
+)
+
+func testEntries() []entry {
+	var entries []entry
+	for _, item := range []struct {
+		key, project, day string
+		minutes, billable int
+	}{
+		{"NTC-1", "NTC", "2020-10-05", 60, 60},
+		{"NTC-1", "NTC", "2020-10-06", 30, 30},
+		{"NTC-2", "NTC", "2020-10-06", 45, 0},
+		{"OPS-7", "OPS", "2020-10-12", 120, 120},
+	} {
+		w, issue := testWorklog(item.key, item.project, "summary", "ACME")
+		w.StartDate = item.day
+		w.TimeSpentSeconds = item.minutes * 60
+		w.BillableSeconds = item.billable * 60
+		issue.Fields.Project.Name = item.project + " Project"
+		entries = append(entries, entry{worklog: w, issue: issue})
+	}
+	return entries
+}
+
+func Test_groupedReport(t *testing.T) {
+	levels, names, err := parseGroupBy("project, week", defaultAccountAttribute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := groupedReport("test", testEntries(), levels, names, nil)
+
+	var buf bytes.Buffer
+	if err := shared.Render(&buf, shared.FormatCSV, r); err != nil {
+		t.Fatal(err)
+	}
+	expected := "project,week,minutes,billable_minutes,non_billable_minutes,subtotal\n" +
+		"NTC,2020-W41,135,90,45,\n" +
+		"NTC,,135,90,45,project\n" +
+		"OPS,2020-W42,120,120,0,\n" +
+		"OPS,,120,120,0,project\n"
+	if buf.String() != expected {
+		t.Fatalf("ERROR: grouped report:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+	if r.Footer[2] != 255 || r.Footer[3] != 210 || r.Footer[4] != 45 {
+		t.Fatalf("ERROR: totals %v, expected 255 logged of which 210 billable", r.Footer)
+	}
+
+	if _, _, err := parseGroupBy("project,sprint", defaultAccountAttribute); err == nil {
+		t.Fatal("ERROR: expected an unknown level to be rejected")
+	}
+	t.Logf("SUCCESS: grouped %d rows", len(r.Rows))
+}
+
+func Test_pivotReport(t *testing.T) {
+	levels, _, err := parseGroupBy("", defaultAccountAttribute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := time.UTC
+	period := shared.DateRange{
+		From: time.Date(2020, time.October, 5, 0, 0, 0, 0, loc),
+		To:   time.Date(2020, time.October, 7, 0, 0, 0, 0, loc),
+	}
+	r := pivotReport("test", period, testEntries(), levels[0], nil)
+
+	var buf bytes.Buffer
+	if err := shared.Render(&buf, shared.FormatTable, r); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"10-05", "10-07", "| NTC-1   | 1h 0m | 0h 30m |       | 1h 30m | 1h 30m   | 0h 0m        |", "OPS-7"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("ERROR: pivot is missing %q:\n%s", want, buf.String())
+		}
+	}
+	t.Logf("SUCCESS: pivoted %d rows", len(r.Rows))
+}
+
+func Test_groupedReport_cost(t *testing.T) {
+	cfg := fake.Settings(t, "[worklog]\ncurrency = USD\n[worklog.rates]\nNTC = 100\ndefault = 50\n")
+	costs, err := newRates(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := testEntries()
+	for i := range entries {
+		entries[i].rate = costs.rate(entries[i].issue.Fields.Project.Key)
+	}
+	levels, names, err := parseGroupBy("project", defaultAccountAttribute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := groupedReport("test", entries, levels, names, costs)
+
+	var buf bytes.Buffer
+	if err := shared.Render(&buf, shared.FormatTable, r); err != nil {
+		t.Fatal(err)
+	}
+	// Only the billable 1h 30m of NTC is charged, OPS falls back to the default.
+	for _, want := range []string{"COST (USD)", "150.00", "100.00", "250.00"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("ERROR: report is missing %q:\n%s", want, buf.String())
+		}
+	}
+
+	if _, err := newRates(fake.Settings(t, "[worklog.rates]\nNTC = lots\n")); err == nil {
+		t.Fatal("ERROR: expected an invalid rate to be rejected")
+	}
+	t.Logf("SUCCESS: costed %d rows", len(r.Rows))
+}
@@ -0,0 +1,423 @@
+package worklog
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jokelyo/argparse"
+	"github.com/josh5276/halp/core/keyring"
+	"github.com/josh5276/halp/shared"
+	"github.com/josh5276/halp/shared/atlassian"
+
+	"github.com/josh5276/halp/core"
+)
+
+// importSection is the settings.ini section holding the default column mapping
+// of worklog import, e.g.
+//
+//	[worklog.import]
+//	columns = issue=Ticket, duration=Hours, description=Notes
+const importSection = "worklog.import"
+
+// The import results reported for each row.
+const (
+	resultLogged    = "logged"
+	resultWouldLog  = "would log"
+	resultDuplicate = "duplicate"
+	resultInvalid   = "invalid"
+	resultFailed    = "failed"
+)
+
+type (
+	// importArgs holds the options of worklog import.
+	importArgs struct {
+		file    *core.Args
+		columns *string
+		dryRun  *bool
+	}
+
+	// importRow is a row of the timesheet, along with what became of it.
+	importRow struct {
+		row    int
+		req    atlassian.WorklogRequest
+		result string
+		detail string
+	}
+)
+
+var (
+	imports importArgs
+
+	// importFields lists the worklog fields a timesheet column can map to, with
+	// the headers recognised without a mapping.
+	importFields = []struct {
+		name     string
+		required bool
+		headers  []string
+	}{
+		{"issue", true, []string{"issue", "issue key", "key", "jira id", "ticket"}},
+		{"date", true, []string{"date", "day", "start date"}},
+		{"duration", true, []string{"duration", "time spent", "time", "hours"}},
+		{"start", false, []string{"start", "start time"}},
+		{"description", false, []string{"description", "comment", "notes", "note"}},
+	}
+)
+
+// importPlugin logs the worklogs of a CSV or JSON timesheet.
+func importPlugin(p *argparse.Command) core.Plugin {
+	imports.file = core.Positional("FILE")
+	cmd := p.NewCommand("import", "Log the worklogs of a CSV or JSON timesheet: import FILE, "+
+		"skipping the ones already in Tempo.")
+	imports.columns = cmd.String("", "columns", &argparse.Options{
+		Help: "Comma separated field=header mapping of the columns, e.g. issue=Ticket,duration=Hours. " +
+			"Fields: issue, date, duration, start, description",
+	})
+	imports.dryRun = cmd.Flag("", "dry-run", &argparse.Options{Help: "Show what would be logged without logging it"})
+	return core.Plugin{CMD: cmd, Func: importFunc, Args: imports.file}
+}
+
+// importFunc function is executed from the caller
+func importFunc(ctx context.Context, env core.Env) error {
+	mapping, err := columnMapping(env.Settings, *imports.columns)
+	if err != nil {
+		return core.WithCode(core.ExitUsage, err)
+	}
+	records, err := readTimesheet(imports.file.Get(0))
+	if err != nil {
+		return core.WithCode(core.ExitUsage, err)
+	}
+	rows, err := parseRows(records, mapping)
+	if err != nil {
+		return core.WithCode(core.ExitUsage, err)
+	}
+
+	atl, err := env.Atlassian()
+	if err != nil {
+		return core.WithCode(core.ExitConfig, err)
+	}
+	accountID, err := atl.AccountID(ctx)
+	if err != nil {
+		return core.WithCode(core.ExitAPI, err)
+	}
+	if err := validateIssues(ctx, atl, rows); err != nil {
+		return core.WithCode(core.ExitAPI, err)
+	}
+	existing, err := existingWorklogs(ctx, atl, rows, accountID)
+	if err != nil {
+		return core.WithCode(core.ExitAPI, err)
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		if row.result != "" {
+			continue
+		}
+		row.req.AuthorAccountID = accountID
+		if existing.take(row.req) {
+			row.result, row.detail = resultDuplicate, "already in Tempo, skipped"
+			continue
+		}
+		if *imports.dryRun {
+			row.result = resultWouldLog
+			continue
+		}
+		worklog, err := atl.NewWorklog(ctx, row.req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			row.result, row.detail = resultFailed, err.Error()
+			continue
+		}
+		row.result, row.detail = resultLogged, fmt.Sprintf("worklog %d", worklog.TempoWorklogID)
+	}
+
+	r, failed := importReport(imports.file.Get(0), rows)
+	if err := shared.Render(env.Stdout, env.Output, r); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return core.Errorf(core.ExitError, "jira.worklog.import:%d of %d rows could not be logged", failed, len(rows))
+	}
+	return nil
+}
+
+// columnMapping resolves the field=header pairs of the --columns flag, or else
+// of the [worklog.import] settings, keyed by field.
+func columnMapping(cfg keyring.Settings, flag string) (map[string]string, error) {
+	pairs := cfg.Values(importSection, "columns")
+	if flag != "" {
+		pairs = strings.Split(flag, ",")
+	}
+	mapping := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		field := strings.ToLower(strings.TrimSpace(parts[0]))
+		known := false
+		for _, f := range importFields {
+			known = known || f.name == field
+		}
+		if len(parts) != 2 || !known || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("jira.worklog.import:invalid column mapping %q, expected field=header "+
+				"with field one of issue, date, duration, start or description", strings.TrimSpace(pair))
+		}
+		mapping[field] = strings.TrimSpace(parts[1])
+	}
+	return mapping, nil
+}
+
+// readTimesheet reads the rows of a CSV file, or of a JSON file holding an array
+// of objects, as maps keyed by the lower cased header.
+func readTimesheet(path string) ([]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("jira.worklog.import:%s", err)
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var objects []map[string]interface{}
+		if err := json.NewDecoder(f).Decode(&objects); err != nil {
+			return nil, fmt.Errorf("jira.worklog.import:%s:expected an array of objects:%s", path, err)
+		}
+		records := make([]map[string]string, 0, len(objects))
+		for _, object := range objects {
+			record := make(map[string]string, len(object))
+			for key, value := range object {
+				switch v := value.(type) {
+				case nil:
+				case float64:
+					record[strings.ToLower(key)] = strconv.FormatFloat(v, 'f', -1, 64)
+				default:
+					record[strings.ToLower(key)] = fmt.Sprint(v)
+				}
+			}
+			records = append(records, record)
+		}
+		return records, nil
+	}
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("jira.worklog.import:%s:reading the header:%s", path, err)
+	}
+	records := make([]map[string]string, 0)
+	for {
+		line, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("jira.worklog.import:%s:%s", path, err)
+		}
+		record := make(map[string]string, len(header))
+		for i, value := range line {
+			if i < len(header) {
+				record[strings.ToLower(strings.TrimSpace(header[i]))] = value
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// parseRows builds the worklog of every record, marking the rows that can't be
+// parsed as invalid. It fails when a required field has no column at all.
+func parseRows(records []map[string]string, mapping map[string]string) ([]importRow, error) {
+	columns := make(map[string]string, len(importFields))
+	for _, field := range importFields {
+		headers := field.headers
+		header, mapped := mapping[field.name]
+		if mapped {
+			headers = []string{strings.ToLower(header)}
+		}
+		for _, header := range headers {
+			if hasColumn(records, header) {
+				columns[field.name] = header
+				break
+			}
+		}
+		if _, ok := columns[field.name]; !ok && len(records) > 0 && (field.required || mapped) {
+			return nil, fmt.Errorf("jira.worklog.import:no %s column found, map one with --columns %s=<header>",
+				field.name, field.name)
+		}
+	}
+
+	rows := make([]importRow, 0, len(records))
+	for i, record := range records {
+		row := importRow{row: i + 1}
+		value := func(field string) string { return strings.TrimSpace(record[columns[field]]) }
+		if err := row.parse(value); err != nil {
+			row.result, row.detail = resultInvalid, err.Error()
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// hasColumn reports whether any of the records has the column.
+func hasColumn(records []map[string]string, column string) bool {
+	for _, record := range records {
+		if _, ok := record[column]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parse fills in the worklog of the row from its values.
+func (r *importRow) parse(value func(field string) string) error {
+	r.req.IssueKey = strings.ToUpper(value("issue"))
+	if r.req.IssueKey == "" {
+		return errors.New("no issue")
+	}
+
+	day, err := time.Parse(shared.DateLayout, value("date"))
+	if err != nil {
+		return fmt.Errorf("date %q is not a %s date", value("date"), shared.DateLayout)
+	}
+	r.req.StartDate = day.Format(shared.DateLayout)
+
+	// Spreadsheets tend to hold the hours as a plain number.
+	duration := value("duration")
+	if _, err := strconv.ParseFloat(duration, 64); err == nil {
+		duration += "h"
+	}
+	spent, err := shared.ParseDuration(duration)
+	if err != nil {
+		return fmt.Errorf("duration %q is not a duration such as 1h30m or 1.5", value("duration"))
+	}
+	r.req.TimeSpentSeconds = int(spent / time.Second)
+
+	if start := value("start"); start != "" {
+		layout := "15:04"
+		if strings.Count(start, ":") == 2 {
+			layout = "15:04:05"
+		}
+		at, err := time.Parse(layout, start)
+		if err != nil {
+			return fmt.Errorf("start %q is not a HH:MM time", start)
+		}
+		r.req.StartTime = at.Format("15:04:05")
+	}
+
+	r.req.Description = value("description")
+	if r.req.Description == "" {
+		r.req.Description = fmt.Sprintf("Working on issue %s", r.req.IssueKey)
+	}
+	return nil
+}
+
+// validateIssues looks up every issue of the rows, marking the rows of issues
+// that do not exist as invalid.
+func validateIssues(ctx context.Context, atl *atlassian.Client, rows []importRow) error {
+	missing := make(map[string]bool)
+	for _, row := range rows {
+		key := row.req.IssueKey
+		if _, checked := missing[key]; row.result != "" || checked {
+			continue
+		}
+		_, err := atl.JiraIssue(ctx, key)
+		var apiErr *atlassian.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			missing[key] = true
+			continue
+		} else if err != nil {
+			return err
+		}
+		missing[key] = false
+	}
+	for i := range rows {
+		if rows[i].result == "" && missing[rows[i].req.IssueKey] {
+			rows[i].result, rows[i].detail = resultInvalid, fmt.Sprintf("issue %s not found", rows[i].req.IssueKey)
+		}
+	}
+	return nil
+}
+
+// worklogSet holds the existing worklogs the rows are checked against.
+type worklogSet []atlassian.Worklog
+
+// existingWorklogs fetches the worklogs of the account over the dates of the rows.
+func existingWorklogs(ctx context.Context, atl *atlassian.Client, rows []importRow, accountID string) (*worklogSet, error) {
+	dates := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if row.result == "" {
+			dates = append(dates, row.req.StartDate)
+		}
+	}
+	set := make(worklogSet, 0)
+	if len(dates) == 0 {
+		return &set, nil
+	}
+	sort.Strings(dates)
+	worklogs, err := atl.WorkLogs(ctx, dates[len(dates)-1], dates[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, worklog := range worklogs {
+		if worklog.Author.AccountID == "" || worklog.Author.AccountID == accountID {
+			set = append(set, worklog)
+		}
+	}
+	return &set, nil
+}
+
+// take reports whether a worklog matching the request exists, the same issue,
+// day and time spent, and the same start time when the request has one. A
+// worklog is only matched once, so that two equal rows need two worklogs.
+func (s *worklogSet) take(req atlassian.WorklogRequest) bool {
+	for i, worklog := range *s {
+		if worklog.Issue.Key == req.IssueKey && worklog.StartDate == req.StartDate &&
+			worklog.TimeSpentSeconds == req.TimeSpentSeconds &&
+			(req.StartTime == "" || worklog.StartTime == req.StartTime) {
+			*s = append((*s)[:i], (*s)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// importReport lists what became of every row, returning it with the number of
+// rows that were invalid or failed.
+func importReport(path string, rows []importRow) (shared.Report, int) {
+	r := shared.Report{
+		Title: fmt.Sprintf("Import %s", filepath.Base(path)),
+		Columns: []shared.Column{
+			{Name: "ROW"},
+			{Name: "JIRA ID"},
+			{Name: "DATE"},
+			{Name: "HOURS SPENT", Key: "minutes", Format: shared.FormatMinutes},
+			{Name: "RESULT"},
+			{Name: "DETAIL"},
+		},
+	}
+	counts := make(map[string]int)
+	for _, row := range rows {
+		counts[row.result]++
+		r.Rows = append(r.Rows, []interface{}{
+			row.row, row.req.IssueKey, row.req.StartDate, row.req.TimeSpentSeconds / 60, row.result, row.detail,
+		})
+	}
+	summary := make([]string, 0)
+	for _, result := range []string{resultLogged, resultWouldLog, resultDuplicate, resultInvalid, resultFailed} {
+		if counts[result] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[result], result))
+		}
+	}
+	r.Notes = []string{fmt.Sprintf("%d rows: %s", len(rows), strings.Join(summary, ", "))}
+	return r, counts[resultInvalid] + counts[resultFailed]
+}
@@ -0,0 +1,76 @@
+package worklog
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/josh5276/halp/core"
+	"github.com/josh5276/halp/shared/atlassian/fake"
+)
+
+// importResults decodes the result of every row from the JSON import report.
+func importResults(t *testing.T, out string) []string {
+	var doc struct{ Rows []map[string]interface{} }
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("ERROR: decoding %s:%s", out, err)
+	}
+	results := make([]string, 0, len(doc.Rows))
+	for _, row := range doc.Rows {
+		results = append(results, row["result"].(string))
+	}
+	return results
+}
+
+func TestPlugin_worklogImport(t *testing.T) {
+	srv := fake.Start(t, fake.SharedFixture())
+	defer srv.Close()
+
+	expected := []string{"duplicate", "would log", "would log", "invalid", "invalid", "invalid"}
+	out, err := testRun(t, srv, "", "", "import", "testdata/timesheet.csv", "--dry-run", "-o", "json")
+	if core.Code(err) != core.ExitError {
+		t.Fatalf("ERROR: expected the invalid rows to fail the import, got %v", err)
+	}
+	if results := importResults(t, out); len(srv.Logged) != 0 || fmt.Sprint(results) != fmt.Sprint(expected) {
+		t.Fatalf("ERROR: unexpected dry run %v, logged %+v", results, srv.Logged)
+	}
+
+	expected = []string{"duplicate", "logged", "logged", "invalid", "invalid", "invalid"}
+	out, _ = testRun(t, srv, "", "", "import", "testdata/timesheet.csv", "-o", "json")
+	if results := importResults(t, out); len(srv.Logged) != 2 || fmt.Sprint(results) != fmt.Sprint(expected) {
+		t.Fatalf("ERROR: unexpected import %v, logged %+v", results, srv.Logged)
+	}
+	if req := srv.Logged[1]; req.IssueKey != "OPS-7" || req.TimeSpentSeconds != 4500 || req.StartTime != "14:30:00" ||
+		req.Description != "Change window" || req.AuthorAccountID != "abc123" {
+		t.Fatalf("ERROR: unexpected worklog %+v", req)
+	}
+
+	// Importing the same timesheet again finds everything already logged.
+	expected = []string{"duplicate", "duplicate", "duplicate", "invalid", "invalid", "invalid"}
+	out, _ = testRun(t, srv, "", "", "import", "testdata/timesheet.csv", "-o", "json")
+	if results := importResults(t, out); len(srv.Logged) != 2 || fmt.Sprint(results) != fmt.Sprint(expected) {
+		t.Fatalf("ERROR: unexpected second import %v", results)
+	}
+	t.Logf("SUCCESS: imported %+v", srv.Logged)
+}
+
+func TestPlugin_worklogImportJSON(t *testing.T) {
+	srv := fake.Start(t, fake.SharedFixture())
+	defer srv.Close()
+
+	out, err := testRun(t, srv, "", "", "import", "testdata/timesheet.json", "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results := importResults(t, out); fmt.Sprint(results) != "[duplicate logged]" || len(srv.Logged) != 1 ||
+		srv.Logged[0].TimeSpentSeconds != 900 || srv.Logged[0].StartTime != "16:00:00" {
+		t.Fatalf("ERROR: unexpected import %v, logged %+v", results, srv.Logged)
+	}
+
+	for _, columns := range []string{"issue=Nope", "bogus=Ticket", "issue"} {
+		if _, err := testRun(t, srv, "", "", "import", "testdata/timesheet.csv", "--columns", columns); core.Code(err) != core.ExitUsage {
+			t.Errorf("ERROR: --columns %s: expected a usage error, got %v", columns, err)
+		}
+	}
+	t.Logf("SUCCESS: imported %+v", srv.Logged)
+}
@@ -0,0 +1,73 @@
+package worklog
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jokelyo/argparse"
+	"github.com/josh5276/halp/shared"
+
+	"github.com/josh5276/halp/core"
+)
+
+// listPlugin lists the worklogs one by one with their Tempo worklog IDs, the IDs
+// edit and delete take. It takes the date range and filter flags of worklog.
+func listPlugin(p *argparse.Command) core.Plugin {
+	cmd := p.NewCommand("list", "List the worklogs with their IDs, taking the same range and filters as worklog.")
+	return core.Plugin{CMD: cmd, Func: listFunc}
+}
+
+// listFunc function is executed from the caller
+func listFunc(ctx context.Context, env core.Env) error {
+	period, entries, f, err := loadEntries(ctx, env)
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i].worklog, entries[j].worklog
+		if a.StartDate != b.StartDate {
+			return a.StartDate < b.StartDate
+		}
+		if a.StartTime != b.StartTime {
+			return a.StartTime < b.StartTime
+		}
+		return a.TempoWorklogID < b.TempoWorklogID
+	})
+
+	r := shared.Report{
+		Title: fmt.Sprintf("Worklogs %s", period),
+		Columns: []shared.Column{
+			{Name: "ID"},
+			{Name: "DATE"},
+			{Name: "START"},
+			{Name: "JIRA ID"},
+			{Name: "SUMMARY"},
+			{Name: "HOURS SPENT", Key: "minutes", Format: shared.FormatMinutes},
+			{Name: "DESCRIPTION"},
+		},
+		Notes: f.notes(),
+	}
+	total := 0
+	for _, e := range entries {
+		minutes := e.worklog.TimeSpentSeconds / 60
+		total += minutes
+		r.Rows = append(r.Rows, []interface{}{
+			e.worklog.TempoWorklogID,
+			e.worklog.StartDate,
+			strings.TrimSuffix(e.worklog.StartTime, ":00"),
+			e.worklog.Issue.Key,
+			e.issue.Fields.Summary,
+			minutes,
+			e.worklog.Description,
+		})
+	}
+	r.Footer = make([]interface{}, len(r.Columns))
+	r.Footer[0] = "Total"
+	r.Footer[5] = total
+	if err := shared.Render(env.Stdout, env.Output, r); err != nil {
+		return err
+	}
+	return f.incomplete(env.Output)
+}
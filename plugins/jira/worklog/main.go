@@ -1,117 +1,160 @@
 package worklog
 
 import (
+	"context"
 	"fmt"
-	"os"
-	"strings"
 	"time"
 
-	"github.com/jedib0t/go-pretty/table"
 	"github.com/jokelyo/argparse"
-	"github.com/josh5276/halp/core/keyring"
+	"github.com/josh5276/halp/shared"
 	"github.com/josh5276/halp/shared/atlassian"
 
 	"github.com/josh5276/halp/core"
-	"github.com/sirupsen/logrus"
 )
 
+var (
+	// dates holds the date range flags, resolved when the plugin runs.
+	dates *shared.DateRangeArgs
+	// filters holds the overrides for the [worklog.filter] settings.
+	filters filterArgs
+	// groupBy and pivot select how the report aggregates the worklogs.
+	groupBy *string
+	pivot   *bool
+	// threads bounds the number of concurrent issue lookups.
+	threads *int
+)
+
+// section is the settings.ini section holding the worklog options.
+const section = "worklog"
+
 // SubPlugin function will return a argparse.Command type back to the parent parser
 // nolint:typecheck
 func SubPlugin(p *argparse.Command) core.Plugin {
 	// Create a command and argument for the ip audit
-	cmd := p.NewCommand("worklog", "View your current worklog for the month.")
-	return core.Plugin{CMD: cmd, Func: pluginFunc}
-}
-
-type billed struct {
-	timeMinutes int
-	jiraID      string
-	jiraDesc    string
+	cmd := p.NewCommand("worklog", "View your worklog, for the current month unless a range is given.")
+	dates = shared.ArgDateRange(cmd)
+	filters = filterArgs{
+		projects: cmd.StringList("", "project", &argparse.Options{
+			Help: "Only report these project keys, overrides the configured projects",
+		}),
+		summaryMatch: cmd.String("", "summary-match", &argparse.Options{
+			Help: "Only report issues whose summary matches this regex, overrides the configured summary rules",
+		}),
+		all: cmd.Flag("", "all", &argparse.Options{Help: "Report every worklog, ignoring the configured filters"}),
+	}
+	groupBy = cmd.String("", "group-by", &argparse.Options{
+		Help: "Comma separated levels to group by: issue, project, day, week, account. Defaults to issue",
+	})
+	pivot = cmd.Flag("", "pivot", &argparse.Options{
+		Help: "Show a grid of the --group-by level by day instead of a list",
+	})
+	threads = shared.ArgRoutines(cmd, defaultThreads)
+	return core.Plugin{
+		CMD:      cmd,
+		Func:     pluginFunc,
+		Children: core.Nest(cmd, listPlugin, editPlugin, deletePlugin, importPlugin, gapsPlugin),
+	}
 }
 
 // pluginFunc function is executed from the caller
-func pluginFunc(cfg keyring.Settings) {
-	tempoToken, err := cfg.TempoToken()
+func pluginFunc(ctx context.Context, env core.Env) error {
+	accountAttribute := env.Settings.Value(section, "account_attribute")
+	if accountAttribute == "" {
+		accountAttribute = defaultAccountAttribute
+	}
+	levels, names, err := parseGroupBy(*groupBy, accountAttribute)
 	if err != nil {
-		logrus.Fatalf("cfg.Tempo:%s", err)
+		return core.WithCode(core.ExitUsage, err)
+	}
+	if *pivot && len(levels) > 1 {
+		return core.Errorf(core.ExitUsage, "--pivot takes a single --group-by level, got %s", *groupBy)
 	}
 
-	jiraToken, err := cfg.JIRAToken()
+	costs, err := newRates(env.Settings)
 	if err != nil {
-		logrus.Fatalf("cfg.Tempo:%s", err)
+		return core.WithCode(core.ExitConfig, err)
+	}
+
+	period, entries, f, err := loadEntries(ctx, env)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		entries[i].rate = costs.rate(entries[i].issue.Fields.Project.Key)
 	}
+	title := fmt.Sprintf("Worklog %s", period)
+	var r shared.Report
+	if *pivot {
+		r = pivotReport(title, period, entries, levels[0], costs)
+	} else {
+		r = groupedReport(title, entries, levels, names, costs)
+	}
+	r.Notes = f.notes()
+	if err := shared.Render(env.Stdout, env.Output, r); err != nil {
+		return err
+	}
+	return f.incomplete(env.Output)
+}
 
-	atl := atlassian.New(cfg.JIRAUser, jiraToken.Password, tempoToken.Password, cfg.JIRAInstance)
+// loadEntries fetches the user's worklogs of the period given on the command line
+// along with their issues, keeping the ones that pass the filter.
+func loadEntries(ctx context.Context, env core.Env) (shared.DateRange, []entry, *filter, error) {
+	loc, err := env.Settings.Location()
+	if err != nil {
+		return shared.DateRange{}, nil, nil, core.WithCode(core.ExitConfig, err)
+	}
+	period, err := dates.Range(time.Now(), loc)
+	if err != nil {
+		return period, nil, nil, core.WithCode(core.ExitUsage, err)
+	}
+	f, err := newFilter(env.Settings, filters)
+	if err != nil {
+		return period, nil, nil, core.WithCode(core.ExitConfig, err)
+	}
 
-	from, to := getDates()
-	worklogs, err := atl.WorkLogs(to.Format("2006-01-02"), from.Format("2006-01-02"))
+	atl, err := env.Atlassian()
+	if err != nil {
+		return period, nil, nil, core.WithCode(core.ExitConfig, err)
+	}
+	accountID, err := atl.AccountID(ctx)
+	if err != nil {
+		return period, nil, nil, core.WithCode(core.ExitAPI, err)
+	}
+	worklogs, err := atl.WorkLogs(ctx, period.To.Format(shared.DateLayout), period.From.Format(shared.DateLayout))
+	if err != nil {
+		return period, nil, nil, core.WithCode(core.ExitAPI, err)
+	}
+	worklogs = ownWorklogs(worklogs, accountID)
+	issues, failed, err := fetchIssues(ctx, atl, worklogs, *threads)
 	if err != nil {
-		logrus.Fatal(err)
+		return period, nil, nil, err
 	}
-	totalTime := make(map[string]billed)
+	unfetched := make(map[string]bool, len(failed))
+	for _, key := range failed {
+		unfetched[key] = true
+	}
+	entries := make([]entry, 0, len(worklogs))
 	for _, item := range worklogs {
-		issue, err := atl.JiraIssue(item.Issue.Key)
-		if err != nil {
-			logrus.Errorf("Error fetching issue %s, %s", item.Issue.Key, err)
+		if unfetched[item.Issue.Key] {
+			f.unfetched++
 			continue
 		}
-		if strings.Contains(strings.ToUpper(issue.Fields.Summary), "[NTC] DELIVER") {
-			// Add the issue to the map if it doesn't exist
-			if _, ok := totalTime[item.Issue.Key]; !ok {
-				totalTime[item.Issue.Key] = billed{
-					timeMinutes: item.TimeSpentSeconds / 60,
-					jiraID:      item.Issue.Key,
-					jiraDesc:    issue.Fields.Project.Name,
-				}
-			} else {
-				totalTime[item.Issue.Key] = billed{
-					timeMinutes: totalTime[item.Issue.Key].timeMinutes + item.TimeSpentSeconds/60,
-					jiraID:      item.Issue.Key,
-					jiraDesc:    issue.Fields.Project.Name,
-				}
-			}
+		issue, ok := issues[item.Issue.Key]
+		if ok && f.keep(item, issue) {
+			entries = append(entries, entry{worklog: item, issue: issue})
 		}
 	}
-	prettyPrint(totalTime)
-}
-
-// prettyPrint func will take a structured type of response data and render a table
-// output to stdout.
-func prettyPrint(data map[string]billed) {
-	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"JIRA ID", "DESCRIPTION", "HOURS SPENT"})
-
-	totalBilled := 0
-	for issue, value := range data {
-		totalBilled += value.timeMinutes
-		hours := value.timeMinutes / 60
-		minutes := value.timeMinutes % 60
-
-		t.AppendRow(table.Row{
-			issue,
-			value.jiraDesc,
-			fmt.Sprintf("%dh %dm", hours, minutes),
-		})
-	}
-	hours := totalBilled / 60
-	minutes := totalBilled % 60
-	t.AppendFooter(table.Row{
-		"",
-		"Total Billed Time",
-		fmt.Sprintf("%dh %dm", hours, minutes),
-	})
-
-	t.SortBy([]table.SortBy{{Name: "JIRA ID", Mode: table.Asc}})
-	t.SetStyle(table.StyleDefault)
-	t.Render()
+	return period, entries, f, nil
 }
 
-func getDates() (from time.Time, to time.Time) {
-	to = time.Now()
-	currentYear, currentMonth, _ := to.Date()
-	currentLocation := to.Location()
-
-	from = time.Date(currentYear, currentMonth, 1, 0, 0, 0, 0, currentLocation)
-	return
+// ownWorklogs keeps the worklogs logged by the account, Tempo returns those of
+// everyone the token can see.
+func ownWorklogs(worklogs []atlassian.Worklog, accountID string) []atlassian.Worklog {
+	own := worklogs[:0]
+	for _, worklog := range worklogs {
+		if worklog.Author.AccountID == "" || worklog.Author.AccountID == accountID {
+			own = append(own, worklog)
+		}
+	}
+	return own
 }
@@ -0,0 +1,110 @@
+package worklog
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/josh5276/halp/core"
+	"github.com/josh5276/halp/shared/atlassian/fake"
+)
+
+// testRun runs `halp jira worklog` with the arguments passed in against the
+// fake server, answering any confirmation with stdin, and returns what it printed.
+func testRun(t *testing.T, srv *fake.Server, cfg, stdin string, args ...string) (string, error) {
+	return fake.Run(t, srv, SubPlugin, "jira worklog", core.Env{
+		Settings: fake.Settings(t, cfg),
+		Stdin:    strings.NewReader(stdin),
+	}, args...)
+}
+
+func TestPlugin_worklog(t *testing.T) {
+	srv := fake.Start(t, fake.SharedFixture())
+	defer srv.Close()
+
+	out, err := testRun(t, srv, "", "", "--from", "2020-10-01", "--to", "2020-10-31", "--all", "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Title  string
+		Rows   []map[string]interface{}
+		Footer map[string]interface{}
+	}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("ERROR: decoding %s:%s", out, err)
+	}
+	expected := map[string]float64{"NTC-1": 210, "NTC-2": 30, "OPS-7": 60}
+	if len(doc.Rows) != len(expected) || doc.Footer["minutes"] != 300.0 ||
+		doc.Footer["billable_minutes"] != 270.0 || doc.Footer["non_billable_minutes"] != 30.0 {
+		t.Fatalf("ERROR: unexpected report %s", out)
+	}
+	for _, row := range doc.Rows {
+		if row["minutes"] != expected[row["jira_id"].(string)] {
+			t.Errorf("ERROR: unexpected row %v", row)
+		}
+	}
+	t.Logf("SUCCESS: %s", doc.Title)
+}
+
+func TestPlugin_worklogFiltered(t *testing.T) {
+	srv := fake.Start(t, fake.SharedFixture())
+	defer srv.Close()
+
+	cfg := "[worklog.filter]\nsummary = [NTC] DELIVER\n"
+	out, err := testRun(t, srv, cfg, "", "--from", "2020-10-01", "--to", "2020-10-31", "--group-by", "project,day")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"2020-10-01", "2020-10-02", "Subtotal", "3H 30M", "2 worklogs excluded by: summary contains [NTC] DELIVER"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("ERROR: expected %q in\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "2020-10-05") {
+		t.Errorf("ERROR: expected OPS-7 to be filtered out of\n%s", out)
+	}
+	t.Logf("SUCCESS: filtered report\n%s", out)
+}
+
+func TestPlugin_worklogAPIError(t *testing.T) {
+	srv := fake.Start(t, fake.SharedFixture())
+	defer srv.Close()
+
+	srv.Script(fake.Response{
+		Method: http.MethodGet,
+		Path:   "/core/3/worklogs",
+		Status: http.StatusUnauthorized,
+		Body:   `{"errors": [{"message": "The access token is invalid"}]}`,
+	})
+	_, err := testRun(t, srv, "", "", "--from", "2020-10-01", "--to", "2020-10-31")
+	if core.Code(err) != core.ExitAPI || !strings.Contains(err.Error(), "The access token is invalid") {
+		t.Fatalf("ERROR: expected an API error, got %v", err)
+	}
+	t.Logf("SUCCESS: %s", err)
+}
+
+func TestPlugin_worklogUnfetched(t *testing.T) {
+	srv := fake.Start(t, fake.SharedFixture())
+	defer srv.Close()
+
+	failSearch := fake.Response{
+		Method: http.MethodGet,
+		Path:   "/rest/api/2/search",
+		Status: http.StatusBadRequest,
+		Body:   `{"errorMessages": ["Search is down"]}`,
+	}
+	srv.Script(failSearch)
+	out, err := testRun(t, srv, "", "", "--from", "2020-10-01", "--to", "2020-10-31", "--all")
+	if err != nil || !strings.Contains(out, "4 worklogs skipped, their issues could not be fetched") {
+		t.Fatalf("ERROR: expected the table to note the skipped worklogs, %v:\n%s", err, out)
+	}
+
+	srv.Script(failSearch)
+	out, err = testRun(t, srv, "", "", "--from", "2020-10-01", "--to", "2020-10-31", "--all", "-o", "json")
+	if core.Code(err) != core.ExitAPI || !strings.Contains(out, "worklogs skipped") {
+		t.Fatalf("ERROR: expected the JSON report and an API error, got %v:\n%s", err, out)
+	}
+	t.Logf("SUCCESS: %s", err)
+}
@@ -0,0 +1,72 @@
+package worklog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/josh5276/halp/core/keyring"
+)
+
+// ratesSection is the settings.ini section holding the hourly rate billed per
+// project key. default applies to the projects without a rate of their own, e.g.
+//
+//	[worklog.rates]
+//	NTC     = 150
+//	OPS     = 95.50
+//	default = 120
+const ratesSection = "worklog.rates"
+
+// rates is the hourly rate billed per project, used to put a cost on the
+// billable time in the report.
+type rates struct {
+	projects map[string]float64
+	currency string
+}
+
+// newRates reads the rates from the settings, nil when none are configured
+// so the report leaves out the cost.
+func newRates(cfg keyring.Settings) (*rates, error) {
+	keys := cfg.Keys(ratesSection)
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	r := &rates{projects: make(map[string]float64, len(keys)), currency: cfg.Value(section, "currency")}
+	for _, key := range keys {
+		v := cfg.Value(ratesSection, key)
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("worklog.rates:%s = %q is not an hourly rate", key, v)
+		}
+		r.projects[strings.ToUpper(key)] = rate
+	}
+	return r, nil
+}
+
+// rate returns the hourly rate of a project, 0 when it has none and there is no default.
+func (r *rates) rate(project string) float64 {
+	if r == nil {
+		return 0
+	}
+	if rate, ok := r.projects[strings.ToUpper(project)]; ok {
+		return rate
+	}
+	return r.projects["DEFAULT"]
+}
+
+// column is the COST column of the report, named after the currency when one is set.
+func (r *rates) column() string {
+	if r.currency == "" {
+		return "COST"
+	}
+	return fmt.Sprintf("COST (%s)", r.currency)
+}
+
+// formatCost shows a cost with two decimals.
+func formatCost(v interface{}) string {
+	cost, ok := v.(float64)
+	if !ok {
+		return fmt.Sprint(v)
+	}
+	return strconv.FormatFloat(cost, 'f', 2, 64)
+}
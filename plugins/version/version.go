@@ -3,11 +3,14 @@
 package version
 
 import (
+	"context"
+	"fmt"
 	"runtime"
 	"time"
 
+	"github.com/jokelyo/argparse"
 	"github.com/josh5276/halp/core"
-	"github.com/josh5276/halp/core/keyring"
+	"github.com/josh5276/halp/shared"
 	"github.com/sirupsen/logrus"
 
 	"github.com/gookit/color"
@@ -15,24 +18,49 @@ import (
 
 // Plugin function will return a argparse.Command type back to the parent parser
 // nolint:typecheck
-func Plugin(p *core.Parser) core.Plugin {
+func Plugin(p *argparse.Command) core.Plugin {
 	// Create a argument for the DCX Translations logic
 	cmd := p.NewCommand("version", "display current version")
 	return core.Plugin{CMD: cmd, Func: pluginFunc}
 }
 
 // pluginFunc function is executed from the halp caller
-func pluginFunc(cfg keyring.Settings) {
+func pluginFunc(_ context.Context, env core.Env) error {
 	var storedVer CfgVer
-	key, err := FromCfg(cfg)
+	key, err := FromCfg(env.Settings)
 	if err == nil {
 		if storedVer, err = Parse(key.String()); err != nil {
 			logrus.Error(err)
 		}
 	}
 
-	color.Green.Printf("Halp: v%s\n", storedVer.Version.String())
-	color.Cyan.Printf(" ° Runtime: %s_%s\n", runtime.GOOS, runtime.GOARCH)
-	color.Cyan.Printf(" ° Version Checked At: %s\n", storedVer.Timestamp.String())
-	color.Cyan.Printf(" ° Next Version Check At: %s\n\n", storedVer.Timestamp.Add(checkInterval*time.Hour))
+	if env.Output != shared.FormatTable {
+		return shared.Render(env.Stdout, env.Output, report(storedVer))
+	}
+	fmt.Fprint(env.Stdout, color.Green.Sprintf("Halp: v%s\n", storedVer.Version.String()))
+	fmt.Fprint(env.Stdout, color.Cyan.Sprintf(" ° Runtime: %s_%s\n", runtime.GOOS, runtime.GOARCH))
+	fmt.Fprint(env.Stdout, color.Cyan.Sprintf(" ° Version Checked At: %s\n", storedVer.Timestamp.String()))
+	fmt.Fprint(env.Stdout, color.Cyan.Sprintf(" ° Next Version Check At: %s\n\n",
+		storedVer.Timestamp.Add(checkInterval*time.Hour)))
+	return nil
+}
+
+// report converts the version details into the structured form used for the
+// machine readable output formats.
+func report(v CfgVer) shared.Report {
+	return shared.Report{
+		Title: "Halp",
+		Columns: []shared.Column{
+			{Name: "VERSION"},
+			{Name: "RUNTIME"},
+			{Name: "CHECKED AT"},
+			{Name: "NEXT CHECK AT"},
+		},
+		Rows: [][]interface{}{{
+			v.Version.String(),
+			fmt.Sprintf("%s_%s", runtime.GOOS, runtime.GOARCH),
+			v.Timestamp,
+			v.Timestamp.Add(checkInterval * time.Hour),
+		}},
+	}
 }
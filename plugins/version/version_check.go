@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"runtime"
 	"strings"
@@ -17,13 +18,14 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-const (
-	checkInterval = 2
-	versionAPI    = "https://api.github.com/repos/josh5276/halp/tags"
-)
+const checkInterval = 2
+
+// versionAPI lists the released tags of halp, the tests point it at a local server.
+var versionAPI = "https://api.github.com/repos/josh5276/halp/tags"
 
-// Check function is executed from the halp caller
-func Check(cfg keyring.Settings, version string) error {
+// Check function is executed from the halp caller. The upgrade notice is written
+// to w, stderr in halp, so that it never mixes with the output of the command.
+func Check(cfg keyring.Settings, version string, w io.Writer) error {
 	runningVer := SemVer(version)
 	key, err := FromCfg(cfg)
 	if err != nil {
@@ -49,7 +51,7 @@ func Check(cfg keyring.Settings, version string) error {
 		return fmt.Errorf("version check failed: %s", err)
 	}
 	if runningVer.LessThan(apiVer) {
-		Notify(runningVer, apiVer)
+		Notify(w, runningVer, apiVer)
 	}
 	return nil
 }
@@ -202,17 +204,17 @@ func SemVer(s string) *semver.Version {
 
 // Notify is used to print info to terminal if the user needs
 // to be notified of a new or different running version
-func Notify(running, current *semver.Version) {
-	color.LightYellow.Printf("Upgrade available (%s running, %s available). Install with:\n", running, current)
+func Notify(w io.Writer, running, current *semver.Version) {
+	fmt.Fprint(w, color.LightYellow.Sprintf("Upgrade available (%s running, %s available). Install with:\n", running, current))
 	switch runtime.GOOS {
 	case "linux":
-		color.LightYellow.Printf("   >> curl -O https://<package_url>/halp_64-bit.deb "+
-			"&& sudo dpkg -i halp_64-bit.deb\n", current)
-		color.Yellow.Printf("You will be notified in %d hours if you have not upgraded.\n", checkInterval)
+		fmt.Fprint(w, color.LightYellow.Sprint("   >> curl -O https://<package_url>/halp_64-bit.deb "+
+			"&& sudo dpkg -i halp_64-bit.deb\n"))
+		fmt.Fprint(w, color.Yellow.Sprintf("You will be notified in %d hours if you have not upgraded.\n", checkInterval))
 	case "darwin":
-		color.LightYellow.Printf("   >> brew update && brew upgrade halp\n")
-		color.Yellow.Printf("You will be notified in %d hours if you have not upgraded.\n", checkInterval)
+		fmt.Fprint(w, color.LightYellow.Sprint("   >> brew update && brew upgrade halp\n"))
+		fmt.Fprint(w, color.Yellow.Sprintf("You will be notified in %d hours if you have not upgraded.\n", checkInterval))
 	default:
-		color.Yellow.Print("Unknown OS, check https://github.com/josh5276/halp for install options\n")
+		fmt.Fprint(w, color.Yellow.Sprint("Unknown OS, check https://github.com/josh5276/halp for install options\n"))
 	}
 }
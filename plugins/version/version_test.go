@@ -1,6 +1,9 @@
 package version
 
 import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -22,16 +25,35 @@ func TestCfgVer_String(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if verStruct.Version != testCfgVer.Version {
+	if !verStruct.Version.Equal(testCfgVer.Version) {
 		t.Errorf("Parsing error %s != %s", verStruct.Version, testCfgVer.Version)
 	}
 	t.Logf("SUCCESS: CfgVer paresed: %s, Timestamp: %s", verStruct.Version, verStruct.Timestamp)
 }
 
 func TestFromAPI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"name": "v1.2.0"}, {"name": "v1.10.1"}, {"name": "v1.9.0"}]`))
+	}))
+	defer srv.Close()
+	defer func(api string) { versionAPI = api }(versionAPI)
+	versionAPI = srv.URL
+
 	apiVer, err := FromAPI()
 	if err != nil {
 		t.Fatal(err)
 	}
+	if apiVer.String() != "1.10.1" {
+		t.Fatalf("ERROR: expected v1.10.1 as the latest version, got v%s", apiVer)
+	}
 	t.Logf("SUCCESS: Found v%s as latest version", apiVer.String())
 }
+
+func TestNotify(t *testing.T) {
+	var buf bytes.Buffer
+	Notify(&buf, SemVer("v1.2.0"), SemVer("v1.10.1"))
+	if !strings.Contains(buf.String(), "Upgrade available (1.2.0 running, 1.10.1 available)") {
+		t.Fatalf("ERROR: unexpected notice %q", buf.String())
+	}
+	t.Logf("SUCCESS: notified of the upgrade")
+}
@@ -17,3 +17,16 @@ func ArgRoutines(cmd *argparse.Command, def int) *int {
 		&argparse.Options{Help: "Number of concurrent processes to run", Default: def}, // Argument options
 	)
 }
+
+// ArgDateRange registers the flags used to select a range of days on a command.
+// Resolve them with DateRangeArgs.Range once the arguments have been parsed.
+func ArgDateRange(cmd *argparse.Command) *DateRangeArgs {
+	return &DateRangeArgs{
+		from:      cmd.String("", "from", &argparse.Options{Help: "First day of the range (YYYY-MM-DD)"}),
+		to:        cmd.String("", "to", &argparse.Options{Help: "Last day of the range (YYYY-MM-DD), defaults to today"}),
+		lastMonth: cmd.Flag("", "last-month", &argparse.Options{Help: "Use the whole of last month"}),
+		thisWeek:  cmd.Flag("", "this-week", &argparse.Options{Help: "Use Monday of this week through today"}),
+		lastWeek:  cmd.Flag("", "last-week", &argparse.Options{Help: "Use Monday through Sunday of last week"}),
+		days:      cmd.Int("", "days", &argparse.Options{Help: "Use the last N days, including today"}),
+	}
+}
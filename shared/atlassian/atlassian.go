@@ -1,33 +1,182 @@
 package atlassian
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 )
 
-// client : Stored memory objects for the Atlassian client.
-type client struct {
-	jiraUser   string
-	jiraToken  string
-	tempoToken string
-	instance   string
-	client     http.Client
-	jiraIssues map[string]JIRAIssue
-}
+const (
+	// AuthBasic : JIRA basic auth with the username and an API token, used by JIRA Cloud.
+	AuthBasic AuthScheme = "basic"
+	// AuthBearer : JIRA bearer auth with a personal access token, used by JIRA Data Center.
+	AuthBearer AuthScheme = "bearer"
+
+	// SearchEnhanced : The JQL search of JIRA Cloud, /search/jql, which pages with a
+	// nextPageToken and doesn't count the issues found.
+	SearchEnhanced SearchAPI = "enhanced"
+	// SearchLegacy : The JQL search of JIRA Data Center, /search, which pages with startAt
+	// and counts the issues found. JIRA Cloud has retired it.
+	SearchLegacy SearchAPI = "legacy"
+
+	// DefaultJiraAPIVersion : The version of the JIRA REST API used unless configured.
+	DefaultJiraAPIVersion = "2"
+	// DefaultTempoURL : The Tempo Cloud API used unless configured.
+	DefaultTempoURL = "https://api.tempo.io"
+	// DefaultTempoAPIVersion : The version of the Tempo API used unless configured.
+	DefaultTempoAPIVersion = "core/3"
+)
+
+type (
+	// AuthScheme : How the client authenticates with JIRA.
+	AuthScheme string
+
+	// SearchAPI : Which JIRA search API the client runs JQL queries with.
+	SearchAPI string
+
+	// Cache : Persistent store the client keeps issues in between runs, see the
+	// shared/cache package. The in-memory cache is always checked first.
+	Cache interface {
+		Get(bucket, key string, v interface{}) bool
+		Put(bucket, key string, modified time.Time, v interface{}) error
+		Keys(bucket string) []string
+	}
+
+	// Options : Everything needed to create a Client. Only JiraURL is required,
+	// the other fields fall back to their defaults when left empty.
+	Options struct {
+		// JiraURL is the base URL of the JIRA instance, e.g. https://acme.atlassian.net.
+		// A bare host name is taken to be https.
+		JiraURL        string
+		JiraAPIVersion string
+		JiraAuth       AuthScheme
+		JiraUser       string
+		JiraToken      string
+		// JiraSearch defaults to SearchEnhanced on JIRA Cloud (*.atlassian.net)
+		// and to SearchLegacy anywhere else.
+		JiraSearch SearchAPI
+		// TempoURL is the base URL of the Tempo API, e.g. https://api.eu.tempo.io.
+		TempoURL        string
+		TempoAPIVersion string
+		TempoToken      string
+		// AccountID is the JIRA account new worklogs are logged as, looked up
+		// from the authenticated user when left empty.
+		AccountID string
+		// Retry defaults to DefaultRetryPolicy.
+		Retry *RetryPolicy
+		// Cache, when set, keeps the fetched issues between runs.
+		Cache Cache
+		// HTTPClient defaults to a client with a 10 second timeout.
+		HTTPClient *http.Client
+	}
+
+	// Client : Stored memory objects for the Atlassian client. The client is safe
+	// for concurrent use; mu guards the jiraIssues cache and the accountID.
+	Client struct {
+		jiraURL    string
+		jiraAPI    string
+		auth       AuthScheme
+		jiraUser   string
+		jiraToken  string
+		tempoAPI   string
+		tempoToken string
+		// adf is set on JIRA API version 3, which takes rich text as ADF documents.
+		adf    bool
+		search SearchAPI
+		// instance identifies the JIRA instance in the persistent cache.
+		instance   string
+		client     *http.Client
+		mu         sync.RWMutex
+		jiraIssues map[string]JIRAIssue
+		accountID  string
+		cache      Cache
+		retry      RetryPolicy
+	}
+)
 
 // New : Function used to create a new Atlassian client data type.
-func New(jiraUser, jiraToken, tempoToken, instance string) *client {
-	return &client{
-		jiraUser:   jiraUser,
-		jiraToken:  jiraToken,
-		tempoToken: tempoToken,
-		instance:   instance,
-		client: http.Client{
+func New(opts Options) (*Client, error) {
+	jiraURL, err := baseURL(opts.JiraURL)
+	if err != nil {
+		return nil, fmt.Errorf("atlassian.New:jira url:%s", err)
+	}
+	tempoURL, err := baseURL(orDefault(opts.TempoURL, DefaultTempoURL))
+	if err != nil {
+		return nil, fmt.Errorf("atlassian.New:tempo url:%s", err)
+	}
+	auth := AuthScheme(strings.ToLower(orDefault(string(opts.JiraAuth), string(AuthBasic))))
+	if auth != AuthBasic && auth != AuthBearer {
+		return nil, fmt.Errorf("atlassian.New:unknown auth scheme %q, use %s or %s", opts.JiraAuth, AuthBasic, AuthBearer)
+	}
+
+	search := SearchAPI(strings.ToLower(string(opts.JiraSearch)))
+	if search == "" {
+		search = SearchLegacy
+		if strings.HasSuffix(jiraURL.Hostname(), ".atlassian.net") {
+			search = SearchEnhanced
+		}
+	}
+	if search != SearchEnhanced && search != SearchLegacy {
+		return nil, fmt.Errorf("atlassian.New:unknown search API %q, use %s or %s", opts.JiraSearch, SearchEnhanced, SearchLegacy)
+	}
+
+	jiraVersion := strings.Trim(orDefault(opts.JiraAPIVersion, DefaultJiraAPIVersion), "/")
+	c := &Client{
+		jiraURL:    jiraURL.String(),
+		jiraAPI:    fmt.Sprintf("%s/rest/api/%s", jiraURL, jiraVersion),
+		adf:        jiraVersion == "3",
+		search:     search,
+		auth:       auth,
+		jiraUser:   opts.JiraUser,
+		jiraToken:  opts.JiraToken,
+		tempoAPI:   fmt.Sprintf("%s/%s", tempoURL, strings.Trim(orDefault(opts.TempoAPIVersion, DefaultTempoAPIVersion), "/")),
+		tempoToken: opts.TempoToken,
+		instance:   jiraURL.Host + jiraURL.Path,
+		client:     opts.HTTPClient,
+		jiraIssues: make(map[string]JIRAIssue),
+		accountID:  strings.TrimSpace(opts.AccountID),
+		cache:      opts.Cache,
+		retry:      DefaultRetryPolicy,
+	}
+	if opts.Retry != nil {
+		c.retry = *opts.Retry
+	}
+	if c.client == nil {
+		c.client = &http.Client{
 			Transport: &http.Transport{
 				MaxIdleConnsPerHost: 20,
 			},
 			Timeout: 10 * time.Second,
-		},
-		jiraIssues: make(map[string]JIRAIssue),
+		}
+	}
+	return c, nil
+}
+
+// baseURL : Parses a configured base URL, defaulting the scheme to https.
+func baseURL(raw string) (*url.URL, error) {
+	raw = strings.TrimRight(strings.TrimSpace(raw), "/")
+	if raw == "" {
+		return nil, fmt.Errorf("no url given")
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%q has no host", raw)
+	}
+	return u, nil
+}
+
+func orDefault(value, def string) string {
+	if strings.TrimSpace(value) == "" {
+		return def
 	}
+	return strings.TrimSpace(value)
 }
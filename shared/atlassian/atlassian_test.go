@@ -0,0 +1,104 @@
+package atlassian
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-ini/ini"
+	"github.com/josh5276/halp/core/keyring"
+	"github.com/josh5276/halp/shared/cache"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		jiraAPI  string
+		tempoAPI string
+		instance string
+		auth     string
+		search   SearchAPI
+		fail     bool
+	}{
+		{
+			name:     "cloud",
+			opts:     Options{JiraURL: "acme.atlassian.net", JiraUser: "me@acme.com", JiraToken: "secret"},
+			jiraAPI:  "https://acme.atlassian.net/rest/api/2",
+			tempoAPI: "https://api.tempo.io/core/3",
+			instance: "acme.atlassian.net",
+			auth:     "Basic bWVAYWNtZS5jb206c2VjcmV0",
+			search:   SearchEnhanced,
+		},
+		{
+			name: "data center",
+			opts: Options{
+				JiraURL:         "https://jira.example.com/jira/",
+				JiraAPIVersion:  "latest",
+				JiraAuth:        "Bearer",
+				JiraToken:       "pat",
+				TempoURL:        "http://localhost:8080",
+				TempoAPIVersion: "/4/",
+			},
+			jiraAPI:  "https://jira.example.com/jira/rest/api/latest",
+			tempoAPI: "http://localhost:8080/4",
+			instance: "jira.example.com/jira",
+			auth:     "Bearer pat",
+			search:   SearchLegacy,
+		},
+		{name: "no url", opts: Options{}, fail: true},
+		{name: "bad auth", opts: Options{JiraURL: "acme.atlassian.net", JiraAuth: "oauth"}, fail: true},
+		{name: "bad search", opts: Options{JiraURL: "acme.atlassian.net", JiraSearch: "v4"}, fail: true},
+	}
+	for _, test := range tests {
+		c, err := New(test.opts)
+		if (err != nil) != test.fail {
+			t.Fatalf("ERROR: %s: unexpected error %v", test.name, err)
+		}
+		if test.fail {
+			continue
+		}
+		if c.jiraAPI != test.jiraAPI || c.tempoAPI != test.tempoAPI || c.instance != test.instance || c.search != test.search {
+			t.Errorf("ERROR: %s: got %s, %s, %s, %s", test.name, c.jiraAPI, c.tempoAPI, c.instance, c.search)
+		}
+		req, _ := http.NewRequest(http.MethodGet, c.jiraAPI, nil)
+		c.jiraAuth(req)
+		if auth := req.Header.Get("Authorization"); auth != test.auth {
+			t.Errorf("ERROR: %s: expected Authorization %q, got %q", test.name, test.auth, auth)
+		}
+	}
+	t.Logf("SUCCESS: built clients for every configuration")
+}
+
+func TestCachedProjects(t *testing.T) {
+	dir, err := ioutil.TempDir("", "halp-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := cache.New(dir, time.Hour, time.Hour)
+	for _, key := range []string{"NTC-1", "OPS-7", "NTC-12", "MY-PROJ-3"} {
+		if err := store.Put("jira-issues/acme.atlassian.net", key, time.Time{}, JIRAIssue{Key: key}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Put("jira-issues/other.atlassian.net", "OTHER-1", time.Time{}, JIRAIssue{}); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := ini.InsensitiveLoad([]byte("[cache]\ndir = " + dir + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	projects, err := CachedProjects(keyring.Settings{File: file, JIRAInstance: "acme.atlassian.net"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(projects, ",") != "MY-PROJ,NTC,OPS" {
+		t.Fatalf("ERROR: expected the projects of acme only, got %v", projects)
+	}
+	t.Logf("SUCCESS: listed the cached projects %v", projects)
+}
@@ -0,0 +1,119 @@
+package atlassian
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// maxErrorBody bounds how much of a failed response is read for its error details.
+const maxErrorBody = 1 << 20
+
+// APIError : Returned by the client when JIRA or Tempo answers a request with an
+// error status. Messages holds the general errors of the response (JIRA errorMessages,
+// Tempo errors) and Fields the JIRA errors keyed by the field they concern. Use
+// errors.As to get at it through the errors wrapping it.
+type APIError struct {
+	// Op is the client method that failed, e.g. jira.NewIssue.
+	Op         string
+	Method     string
+	Endpoint   string
+	StatusCode int
+	Status     string
+	Messages   []string
+	Fields     map[string]string
+}
+
+// errorBody : The error payloads of the JIRA and Tempo APIs. JIRA sends errorMessages
+// and an errors object keyed by field, Tempo an errors array of messages.
+type errorBody struct {
+	ErrorMessages []string        `json:"errorMessages"`
+	Errors        json.RawMessage `json:"errors"`
+	Message       string          `json:"message"`
+}
+
+// Error : Prints the status, the endpoint and every message of the response on one line.
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s:%s (%s %s)", e.Op, e.Status, e.Method, e.Endpoint)
+	if details := e.Details(); len(details) > 0 {
+		msg += ": " + strings.Join(details, "; ")
+	}
+	return msg
+}
+
+// Details : The messages of the response followed by the field errors, ordered by field.
+func (e *APIError) Details() []string {
+	details := append([]string{}, e.Messages...)
+	fields := make([]string, 0, len(e.Fields))
+	for field := range e.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		details = append(details, fmt.Sprintf("%s: %s", field, e.Fields[field]))
+	}
+	return details
+}
+
+// newAPIError : Builds the error for a failed response, reading the error details
+// from its body. The body is left for the caller to close.
+func newAPIError(r request, res *http.Response) *APIError {
+	e := &APIError{
+		Op:         r.name,
+		Method:     r.method,
+		Endpoint:   endpoint(r.url),
+		StatusCode: res.StatusCode,
+		Status:     res.Status,
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+	if err != nil || len(data) == 0 {
+		return e
+	}
+
+	var body errorBody
+	if err := json.Unmarshal(data, &body); err != nil {
+		// Not JSON, keep short plain text bodies but not HTML error pages.
+		if text := strings.TrimSpace(string(data)); len(text) < 200 && !strings.HasPrefix(text, "<") {
+			e.Messages = append(e.Messages, text)
+		}
+		return e
+	}
+	e.Messages = append(e.Messages, body.ErrorMessages...)
+	if body.Message != "" {
+		e.Messages = append(e.Messages, body.Message)
+	}
+
+	var fields map[string]string
+	var tempo []struct {
+		Message string `json:"message"`
+	}
+	switch {
+	case len(body.Errors) == 0:
+	case json.Unmarshal(body.Errors, &fields) == nil:
+		if len(fields) > 0 {
+			e.Fields = fields
+		}
+	case json.Unmarshal(body.Errors, &tempo) == nil:
+		for _, t := range tempo {
+			if t.Message != "" {
+				e.Messages = append(e.Messages, t.Message)
+			}
+		}
+	}
+	return e
+}
+
+// endpoint : The URL of a request without its query, which can be a long JQL search.
+func endpoint(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	u.RawQuery = ""
+	return u.String()
+}
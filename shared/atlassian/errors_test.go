@@ -0,0 +1,81 @@
+package atlassian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_newAPIError(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		messages []string
+		fields   map[string]string
+	}{
+		{
+			name:     "jira",
+			body:     `{"errorMessages": ["Issue type is invalid"], "errors": {"summary": "You must specify a summary of the issue."}}`,
+			messages: []string{"Issue type is invalid"},
+			fields:   map[string]string{"summary": "You must specify a summary of the issue."},
+		},
+		{
+			name:     "tempo",
+			body:     `{"errors": [{"message": "Worklog must not be null"}, {"message": "Issue not found"}]}`,
+			messages: []string{"Worklog must not be null", "Issue not found"},
+		},
+		{name: "text", body: "Rate limit exceeded", messages: []string{"Rate limit exceeded"}},
+		{name: "html", body: "<html><body>Bad Gateway</body></html>"},
+		{name: "empty"},
+	}
+	r := request{name: "jira.NewIssue", method: http.MethodPost, url: "https://acme.atlassian.net/rest/api/2/issue/?x=1"}
+	for _, test := range tests {
+		res := &http.Response{
+			StatusCode: http.StatusBadRequest,
+			Status:     "400 Bad Request",
+			Body:       ioutil.NopCloser(strings.NewReader(test.body)),
+		}
+		e := newAPIError(r, res)
+		if e.Endpoint != "https://acme.atlassian.net/rest/api/2/issue/" || e.StatusCode != http.StatusBadRequest {
+			t.Errorf("ERROR: %s: unexpected endpoint or status in %+v", test.name, e)
+		}
+		if len(e.Messages) != len(test.messages) || (len(test.messages) > 0 && !reflect.DeepEqual(e.Messages, test.messages)) {
+			t.Errorf("ERROR: %s: expected messages %q, got %q", test.name, test.messages, e.Messages)
+		}
+		if !reflect.DeepEqual(e.Fields, test.fields) {
+			t.Errorf("ERROR: %s: expected fields %v, got %v", test.name, test.fields, e.Fields)
+		}
+	}
+
+	res := &http.Response{
+		StatusCode: http.StatusBadRequest,
+		Status:     "400 Bad Request",
+		Body:       ioutil.NopCloser(strings.NewReader(tests[0].body)),
+	}
+	err := fmt.Errorf("creating issue:%w", newAPIError(r, res))
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Fields["summary"] == "" {
+		t.Fatalf("ERROR: expected errors.As to find the APIError in %v", err)
+	}
+	expected := "jira.NewIssue:400 Bad Request (POST https://acme.atlassian.net/rest/api/2/issue/): " +
+		"Issue type is invalid; summary: You must specify a summary of the issue."
+	if apiErr.Error() != expected {
+		t.Fatalf("ERROR: expected %q, got %q", expected, apiErr.Error())
+	}
+	t.Logf("SUCCESS: %s", apiErr)
+}
+
+func TestClient_doAPIError(t *testing.T) {
+	c, _, done := testClient(t, http.StatusNotFound)
+	defer done()
+	_, err := c.JiraIssue(context.Background(), "NTC-404")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Op != "jira.Issue" {
+		t.Fatalf("ERROR: expected a 404 APIError, got %v", err)
+	}
+}
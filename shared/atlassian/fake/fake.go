@@ -0,0 +1,794 @@
+// Package fake is an in-memory stand-in for the JIRA and Tempo APIs, served
+// over httptest so that the atlassian client and the plugins built on it can
+// be tested offline.
+//
+// The server is seeded from a Fixture, usually loaded from a JSON file under
+// testdata, and answers the endpoints halp uses:
+//
+//	GET  /core/3/worklogs          Tempo worklogs, paginated with metadata.next
+//	POST /core/3/worklogs          logs time to an existing issue
+//	GET  /core/3/worklogs/{id}     a single worklog, PUT replaces it and DELETE removes it
+//	GET  /core/3/worklogs/issue/{key}  the worklogs of an issue, paginated
+//	GET  /rest/api/2/issue/{key}   a single JIRA issue, from Details when it is there
+//	GET  /rest/api/2/issue/createmeta  the create metadata, by projectKeys and issuetypeNames
+//	GET  /rest/api/2/issue/createmeta/{project}/issuetypes[/{id}]  the same per project, paginated
+//	GET  /rest/api/2/project/search  the projects of the create metadata, paginated
+//	GET  /rest/api/2/search        JQL searches, paginated, see match for the JQL understood
+//	GET  /rest/api/2/search/jql    the enhanced search of JIRA Cloud, paginated with nextPageToken
+//	POST /rest/api/2/issue/        creates an issue, numbered per project
+//	GET  /rest/api/2/myself        the user of the fixture
+//
+// The JIRA endpoints answer under /rest/api/3 too, where new issues take their
+// description as an ADF document like JIRA Cloud.
+//
+// Responses can be scripted ahead of the fixture with Script, e.g. to make
+// the next search fail with a 429.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/josh5276/halp/shared/atlassian"
+)
+
+type (
+	// Fixture is the data the server starts with.
+	Fixture struct {
+		Myself     atlassian.User        `json:"myself"`
+		Issues     []atlassian.JIRAIssue `json:"issues"`
+		Worklogs   []atlassian.Worklog   `json:"worklogs"`
+		CreateMeta atlassian.CreateMeta  `json:"createmeta"`
+		// Details holds the full issues returned by key, with their description,
+		// comments and links, for the issues that need more than Issues has.
+		Details map[string]json.RawMessage `json:"details"`
+		// Moved maps the old keys of issues moved to another project or renamed to
+		// their current key. Lookups and key in (...) searches follow them, and
+		// return the issue under its current key the way JIRA does.
+		Moved map[string]string `json:"moved"`
+	}
+
+	// Response is a scripted reply, returned instead of the fixture data for
+	// the next request matching Method and Path.
+	Response struct {
+		Method string
+		Path   string
+		Status int
+		Header map[string]string
+		Body   string
+	}
+
+	// Server is the fake JIRA and Tempo API. Its fields hold what the client
+	// sent, for the tests to assert on.
+	Server struct {
+		*httptest.Server
+		// PageSize is the number of worklogs and issues per page.
+		PageSize int
+		// JiraSearch is the search API of the clients from NewClient.
+		JiraSearch atlassian.SearchAPI
+		// Requests lists every request received, as "METHOD /path".
+		Requests []string
+		// Created holds the issues created through the API.
+		Created []atlassian.IssueRequest
+		// Logged holds the worklogs created through the API.
+		Logged []atlassian.WorklogRequest
+
+		mu       sync.Mutex
+		myself   atlassian.User
+		meta     atlassian.CreateMeta
+		details  map[string]json.RawMessage
+		moved    map[string]string
+		issues   map[string]atlassian.JIRAIssue
+		worklogs []atlassian.Worklog
+		scripts  []Response
+		counters map[string]int
+	}
+)
+
+var (
+	keyIn   = regexp.MustCompile(`(?i)^\s*key\s+in\s*\(([^)]*)\)\s*$`)
+	clause  = regexp.MustCompile(`(?i)^\s*(project|status|assignee|resolution|issuetype|labels)\s*(!=|=)\s*(.+?)\s*$`)
+	orderBy = regexp.MustCompile(`(?i)\s+order\s+by\s+.*$`)
+	jqlAnd  = regexp.MustCompile(`(?i)\s+and\s+`)
+)
+
+// jqlQuote are the quotes JQL values can be wrapped in.
+const jqlQuote = `"'`
+
+// LoadFixture reads a Fixture from a JSON file.
+func LoadFixture(path string) (Fixture, error) {
+	var f Fixture
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return f, err
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return f, fmt.Errorf("fake.LoadFixture:%s:%s", path, err)
+	}
+	return f, nil
+}
+
+// NewServer starts a server seeded with the fixture. Close it when done.
+func NewServer(f Fixture) *Server {
+	s := &Server{
+		PageSize: 2,
+		issues:   make(map[string]atlassian.JIRAIssue),
+		worklogs: f.Worklogs,
+		myself:   f.Myself,
+		meta:     f.CreateMeta,
+		details:  f.Details,
+		moved:    f.Moved,
+		counters: make(map[string]int),
+	}
+	for _, issue := range f.Issues {
+		s.issues[issue.Key] = issue
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/core/3/worklogs", s.worklogsHandler)
+	mux.HandleFunc("/core/3/worklogs/", s.worklogHandler)
+	mux.HandleFunc("/core/3/worklogs/issue/", s.issueWorklogsHandler)
+	for _, api := range []string{"/rest/api/2", "/rest/api/3"} {
+		mux.HandleFunc(api+"/issue/", s.issueHandler)
+		mux.HandleFunc(api+"/issue/createmeta", s.createMetaHandler)
+		mux.HandleFunc(api+"/issue/createmeta/", s.createMetaPageHandler)
+		mux.HandleFunc(api+"/project/search", s.projectsHandler)
+		mux.HandleFunc(api+"/search", s.searchHandler)
+		mux.HandleFunc(api+"/search/jql", s.searchJQLHandler)
+		mux.HandleFunc(api+"/myself", s.myselfHandler)
+	}
+	s.Server = httptest.NewServer(s.middleware(mux))
+	return s
+}
+
+// Options returns the client options pointing both APIs at the server, with
+// retries disabled so that scripted failures surface straight away.
+func (s *Server) Options() atlassian.Options {
+	return atlassian.Options{
+		JiraURL:    s.URL,
+		JiraUser:   "fake@example.com",
+		JiraToken:  "jira-token",
+		JiraSearch: s.JiraSearch,
+		TempoURL:   s.URL,
+		TempoToken: "tempo-token",
+		Retry:      &atlassian.RetryPolicy{},
+		HTTPClient: s.Client(),
+	}
+}
+
+// NewClient returns an atlassian client connected to the server.
+func (s *Server) NewClient() (*atlassian.Client, error) {
+	return atlassian.New(s.Options())
+}
+
+// Script queues a response for the next request matching its method and path.
+func (s *Server) Script(r Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts = append(s.scripts, r)
+}
+
+// middleware logs the request, checks it is authenticated and serves any
+// scripted response before handing it to the fixture handlers.
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.Requests = append(s.Requests, r.Method+" "+r.URL.Path)
+		script, scripted := s.nextScript(r)
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("Authorization") == "" {
+			writeError(w, http.StatusUnauthorized, `{"errorMessages": ["You are not authenticated."]}`)
+			return
+		}
+		if scripted {
+			for k, v := range script.Header {
+				w.Header().Set(k, v)
+			}
+			writeError(w, script.Status, script.Body)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// nextScript pops the first scripted response matching the request.
+func (s *Server) nextScript(r *http.Request) (Response, bool) {
+	for i, script := range s.scripts {
+		if script.Method == r.Method && script.Path == r.URL.Path {
+			s.scripts = append(s.scripts[:i], s.scripts[i+1:]...)
+			return script, true
+		}
+	}
+	return Response{}, false
+}
+
+func (s *Server) worklogsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		s.createWorklog(w, r)
+		return
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "")
+		return
+	}
+	query := r.URL.Query()
+	from, to := query.Get("from"), query.Get("to")
+
+	s.mu.Lock()
+	matched := make([]atlassian.Worklog, 0)
+	for _, worklog := range s.worklogs {
+		if (from == "" || worklog.StartDate >= from) && (to == "" || worklog.StartDate <= to) {
+			matched = append(matched, worklog)
+		}
+	}
+	s.mu.Unlock()
+	s.writeWorklogs(w, r, matched)
+}
+
+// issueWorklogsHandler lists the worklogs of the issue at the end of the path.
+func (s *Server) issueWorklogsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "")
+		return
+	}
+	key := strings.Trim(strings.TrimPrefix(r.URL.Path, "/core/3/worklogs/issue/"), "/")
+	s.mu.Lock()
+	matched := make([]atlassian.Worklog, 0)
+	for _, worklog := range s.worklogs {
+		if worklog.Issue.Key == key {
+			matched = append(matched, worklog)
+		}
+	}
+	s.mu.Unlock()
+	s.writeWorklogs(w, r, matched)
+}
+
+// writeWorklogs writes the page of the worklogs asked for by the offset parameter,
+// linking to the next page when there is one.
+func (s *Server) writeWorklogs(w http.ResponseWriter, r *http.Request, matched []atlassian.Worklog) {
+	query := r.URL.Query()
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	var resp atlassian.WorkLogResponse
+	start, end := bounds(len(matched), offset, s.PageSize)
+	resp.Results = matched[start:end]
+	resp.Metadata.Count = len(resp.Results)
+	resp.Metadata.Offset = offset
+	resp.Metadata.Limit = s.PageSize
+	if offset+s.PageSize < len(matched) {
+		query.Set("offset", strconv.Itoa(offset+s.PageSize))
+		resp.Metadata.Next = fmt.Sprintf("%s%s?%s", s.URL, r.URL.Path, query.Encode())
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) createWorklog(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var worklog atlassian.Worklog
+	for _, existing := range s.worklogs {
+		if existing.TempoWorklogID >= worklog.TempoWorklogID {
+			worklog.TempoWorklogID = existing.TempoWorklogID + 1
+		}
+	}
+	req, ok := s.readWorklog(w, r, &worklog)
+	if !ok {
+		return
+	}
+	s.worklogs = append(s.worklogs, worklog)
+	s.Logged = append(s.Logged, req)
+	writeJSON(w, http.StatusOK, worklog)
+}
+
+func (s *Server) worklogHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(r.URL.Path, "/core/3/worklogs/"), "/"))
+	if err != nil {
+		writeTempoError(w, http.StatusBadRequest, "Invalid worklog id")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := -1
+	for j := range s.worklogs {
+		if s.worklogs[j].TempoWorklogID == id {
+			i = j
+		}
+	}
+	if i < 0 {
+		writeTempoError(w, http.StatusNotFound, fmt.Sprintf("Worklog not found: %d", id))
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.worklogs[i])
+	case http.MethodPut:
+		worklog := atlassian.Worklog{TempoWorklogID: id, CreatedAt: s.worklogs[i].CreatedAt}
+		if _, ok := s.readWorklog(w, r, &worklog); ok {
+			s.worklogs[i] = worklog
+			writeJSON(w, http.StatusOK, worklog)
+		}
+	case http.MethodDelete:
+		s.worklogs = append(s.worklogs[:i], s.worklogs[i+1:]...)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "")
+	}
+}
+
+// readWorklog decodes and validates the worklog request of a POST or PUT into
+// worklog, writing the error response when it is invalid. The caller holds mu.
+func (s *Server) readWorklog(w http.ResponseWriter, r *http.Request, worklog *atlassian.Worklog) (atlassian.WorklogRequest, bool) {
+	var req atlassian.WorklogRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeTempoError(w, http.StatusBadRequest, err.Error())
+		return req, false
+	}
+	issue, ok := s.issues[req.IssueKey]
+	switch {
+	case !ok:
+		writeTempoError(w, http.StatusBadRequest, fmt.Sprintf("Issue not found: %s", req.IssueKey))
+		return req, false
+	case req.TimeSpentSeconds <= 0:
+		writeTempoError(w, http.StatusBadRequest, "timeSpentSeconds must be greater than 0")
+		return req, false
+	case req.AuthorAccountID == "":
+		writeTempoError(w, http.StatusBadRequest, "authorAccountId is required")
+		return req, false
+	}
+	if _, err := time.Parse("2006-01-02", req.StartDate); err != nil {
+		writeTempoError(w, http.StatusBadRequest, fmt.Sprintf("Invalid startDate: %s", req.StartDate))
+		return req, false
+	}
+
+	worklog.Self = fmt.Sprintf("%s/core/3/worklogs/%d", s.URL, worklog.TempoWorklogID)
+	worklog.Issue.Key = issue.Key
+	worklog.Issue.ID, _ = strconv.Atoi(issue.ID)
+	worklog.TimeSpentSeconds = req.TimeSpentSeconds
+	worklog.BillableSeconds = req.TimeSpentSeconds
+	if req.BillableSeconds != nil {
+		worklog.BillableSeconds = *req.BillableSeconds
+	}
+	worklog.StartDate = req.StartDate
+	worklog.StartTime = req.StartTime
+	if worklog.StartTime == "" {
+		worklog.StartTime = "00:00:00"
+	}
+	worklog.Description = req.Description
+	worklog.Author.AccountID = req.AuthorAccountID
+	worklog.Attributes.Values = req.Attributes
+	return req, true
+}
+
+func (s *Server) myselfHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, s.myself)
+}
+
+// createMetaHandler returns the create metadata of the fixture, filtered by the
+// projectKeys and issuetypeNames parameters.
+func (s *Server) createMetaHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "")
+		return
+	}
+	var (
+		project   = r.URL.Query().Get("projectKeys")
+		issueType = r.URL.Query().Get("issuetypeNames")
+		meta      atlassian.CreateMeta
+	)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.meta.Projects {
+		if project != "" && !strings.EqualFold(p.Key, project) {
+			continue
+		}
+		types := p.IssueTypes[:0:0]
+		for _, t := range p.IssueTypes {
+			if issueType == "" || strings.EqualFold(t.Name, issueType) {
+				types = append(types, t)
+			}
+		}
+		p.IssueTypes = types
+		meta.Projects = append(meta.Projects, p)
+	}
+	writeJSON(w, http.StatusOK, meta)
+}
+
+// createMetaPageHandler pages through the issue types of a project, or the fields
+// of one of them by ID, like the per-project create metadata of JIRA Cloud.
+func (s *Server) createMetaPageHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.SplitN(r.URL.Path, "/issue/createmeta/", 2)[1], "/"), "/")
+	if r.Method != http.MethodGet || len(parts) < 2 || len(parts) > 3 || parts[1] != "issuetypes" {
+		writeError(w, http.StatusMethodNotAllowed, "")
+		return
+	}
+	startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+	maxResults, _ := strconv.Atoi(r.URL.Query().Get("maxResults"))
+	if maxResults <= 0 || maxResults > s.PageSize {
+		maxResults = s.PageSize
+	}
+	page := atlassian.CreateMetaPage{StartAt: startAt, MaxResults: maxResults}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.meta.Projects {
+		if !strings.EqualFold(p.Key, parts[0]) {
+			continue
+		}
+		if len(parts) == 2 {
+			types := make([]atlassian.IssueTypeMeta, 0, len(p.IssueTypes))
+			for _, t := range p.IssueTypes {
+				types = append(types, atlassian.IssueTypeMeta{ID: t.ID, Name: t.Name})
+			}
+			start, end := bounds(len(types), startAt, maxResults)
+			page.Total, page.IssueTypes = len(types), types[start:end]
+			writeJSON(w, http.StatusOK, page)
+			return
+		}
+		for _, t := range p.IssueTypes {
+			if t.ID != parts[2] {
+				continue
+			}
+			fields := make([]atlassian.FieldMeta, 0, len(t.Fields))
+			for id, f := range t.Fields {
+				f.FieldID = id
+				fields = append(fields, f)
+			}
+			sort.Slice(fields, func(i, j int) bool { return fields[i].FieldID < fields[j].FieldID })
+			start, end := bounds(len(fields), startAt, maxResults)
+			page.Total, page.Fields = len(fields), fields[start:end]
+			writeJSON(w, http.StatusOK, page)
+			return
+		}
+		writeError(w, http.StatusNotFound, `{"errorMessages": ["Issue type not found."]}`)
+		return
+	}
+	writeError(w, http.StatusNotFound, `{"errorMessages": ["No project could be found with key '`+parts[0]+`'."]}`)
+}
+
+// projectsHandler pages through the projects of the create metadata.
+func (s *Server) projectsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "")
+		return
+	}
+	startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+	maxResults, _ := strconv.Atoi(r.URL.Query().Get("maxResults"))
+	if maxResults <= 0 || maxResults > s.PageSize {
+		maxResults = s.PageSize
+	}
+	s.mu.Lock()
+	projects := make([]atlassian.ProjectMeta, 0, len(s.meta.Projects))
+	for _, p := range s.meta.Projects {
+		projects = append(projects, atlassian.ProjectMeta{Key: p.Key, Name: p.Name})
+	}
+	s.mu.Unlock()
+	start, end := bounds(len(projects), startAt, maxResults)
+	writeJSON(w, http.StatusOK, atlassian.ProjectPage{
+		StartAt:    startAt,
+		MaxResults: maxResults,
+		Total:      len(projects),
+		IsLast:     end == len(projects),
+		Values:     projects[start:end],
+	})
+}
+
+// required lists the custom fields the create metadata requires of an issue type,
+// but the request left out.
+func (s *Server) required(fields atlassian.IssueField, errs map[string]string) {
+	meta, err := s.meta.IssueType(fields.Project.Key, fields.IssueType.Name)
+	if err != nil {
+		return
+	}
+	for id, f := range meta.Fields {
+		if _, ok := fields.Custom[id]; f.Required && strings.HasPrefix(id, "customfield_") && !ok {
+			errs[id] = f.Name + " is required."
+		}
+	}
+}
+
+func (s *Server) issueHandler(w http.ResponseWriter, r *http.Request) {
+	key := s.current(strings.Trim(strings.SplitN(r.URL.Path, "/issue/", 2)[1], "/"))
+	switch {
+	case r.Method == http.MethodGet && key != "":
+		s.mu.Lock()
+		issue, ok := s.issues[key]
+		detail, hasDetail := s.details[key]
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, `{"errorMessages": ["Issue does not exist or you do not have permission to see it."]}`)
+			return
+		}
+		if hasDetail {
+			_, _ = w.Write(detail)
+			return
+		}
+		writeJSON(w, http.StatusOK, issue)
+	case r.Method == http.MethodPost && key == "":
+		s.createIssue(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "")
+	}
+}
+
+func (s *Server) createIssue(w http.ResponseWriter, r *http.Request) {
+	req, ok := readIssue(w, r)
+	if !ok {
+		return
+	}
+	fields := make(map[string]string)
+	if req.Fields.Project.Key == "" {
+		fields["project"] = "project is required"
+	}
+	if strings.TrimSpace(req.Fields.Summary) == "" {
+		fields["summary"] = "You must specify a summary of the issue."
+	}
+	if req.Fields.IssueType.Name == "" {
+		fields["issuetype"] = "issue type is required"
+	}
+	s.mu.Lock()
+	if parent := req.Fields.Parent; parent != nil && s.issues[parent.Key].Key == "" {
+		fields["parent"] = "Could not find issue by id or key."
+	}
+	s.required(req.Fields, fields)
+	if len(fields) > 0 {
+		s.mu.Unlock()
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"errorMessages": []string{}, "errors": fields})
+		return
+	}
+
+	project := strings.ToUpper(req.Fields.Project.Key)
+	key := ""
+	for key == "" || s.issues[key].Key != "" {
+		s.counters[project]++
+		key = fmt.Sprintf("%s-%d", project, s.counters[project])
+	}
+	var issue atlassian.JIRAIssue
+	issue.ID = strconv.Itoa(10000 + len(s.issues) + 1)
+	issue.Key = key
+	issue.Self = fmt.Sprintf("%s/rest/api/2/issue/%s", s.URL, issue.ID)
+	issue.Fields.Summary = req.Fields.Summary
+	issue.Fields.Project.Key = project
+	s.issues[key] = issue
+	s.Created = append(s.Created, req)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, atlassian.IssueResponse{ID: issue.ID, Key: issue.Key, Self: issue.Self})
+}
+
+// readIssue decodes the issue to create. API version 3 takes the description as an
+// ADF document, which is decoded back to its text.
+func readIssue(w http.ResponseWriter, r *http.Request) (atlassian.IssueRequest, bool) {
+	var (
+		req atlassian.IssueRequest
+		raw struct {
+			Fields map[string]json.RawMessage `json:"fields"`
+		}
+	)
+	body, err := ioutil.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &raw)
+	}
+	description, adf := raw.Fields["description"], strings.HasPrefix(r.URL.Path, "/rest/api/3/")
+	if err == nil && adf && len(description) > 0 {
+		if description[0] != '{' {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"errorMessages": []string{}, "errors": map[string]string{
+				"description": "Operation value must be an Atlassian Document (see the Atlassian Document Format)"}})
+			return req, false
+		}
+		delete(raw.Fields, "description")
+		body, err = json.Marshal(raw)
+	}
+	if err == nil {
+		err = json.Unmarshal(body, &req)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf(`{"errorMessages": [%q]}`, err.Error()))
+		return req, false
+	}
+	if adf && len(description) > 0 {
+		req.Fields.Description = atlassian.Text(description)
+	}
+	return req, true
+}
+
+func (s *Server) searchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "")
+		return
+	}
+	query := r.URL.Query()
+	jql := query.Get("jql")
+	validate := query.Get("validateQuery")
+	match, err := s.match(jql, validate != "warn" && validate != "none")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf(`{"errorMessages": [%q]}`, err.Error()))
+		return
+	}
+	startAt, _ := strconv.Atoi(query.Get("startAt"))
+	maxResults, _ := strconv.Atoi(query.Get("maxResults"))
+	if maxResults <= 0 || maxResults > s.PageSize {
+		maxResults = s.PageSize
+	}
+
+	found := s.search(match)
+	start, end := bounds(len(found), startAt, maxResults)
+	writeJSON(w, http.StatusOK, atlassian.SearchResponse{
+		StartAt:    startAt,
+		MaxResults: maxResults,
+		Total:      len(found),
+		Issues:     found[start:end],
+	})
+}
+
+// searchJQLHandler is the enhanced search, which pages with an opaque nextPageToken,
+// here the offset of the page, has no total and rejects unknown keys.
+func (s *Server) searchJQLHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "")
+		return
+	}
+	query := r.URL.Query()
+	match, err := s.match(query.Get("jql"), true)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf(`{"errorMessages": [%q]}`, err.Error()))
+		return
+	}
+	offset, _ := strconv.Atoi(query.Get("nextPageToken"))
+	maxResults, _ := strconv.Atoi(query.Get("maxResults"))
+	if maxResults <= 0 || maxResults > s.PageSize {
+		maxResults = s.PageSize
+	}
+
+	found := s.search(match)
+	start, end := bounds(len(found), offset, maxResults)
+	resp := atlassian.SearchJQLResponse{Issues: found[start:end], IsLast: end == len(found)}
+	if !resp.IsLast {
+		resp.NextPageToken = strconv.Itoa(end)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// search lists the issues matching the filter, sorted by key.
+func (s *Server) search(match func(atlassian.JIRAIssue) bool) []atlassian.JIRAIssue {
+	s.mu.Lock()
+	found := make([]atlassian.JIRAIssue, 0)
+	for _, issue := range s.issues {
+		if match(issue) {
+			found = append(found, issue)
+		}
+	}
+	s.mu.Unlock()
+	sort.Slice(found, func(i, j int) bool { return found[i].Key < found[j].Key })
+	return found
+}
+
+// match turns the JQL into a filter on the issues. It understands clauses
+// joined by AND, each one of key in (...), project, status, issuetype or labels
+// = or != a value, assignee = currentUser() and resolution = Unresolved, and
+// ignores ORDER BY as the issues are always sorted by key. Unknown keys are
+// rejected when strict, like JIRA does unless the query is validated leniently.
+func (s *Server) match(jql string, strict bool) (func(atlassian.JIRAIssue) bool, error) {
+	unsupported := fmt.Errorf("fake: unsupported JQL %q", jql)
+	filters := make([]func(atlassian.JIRAIssue) bool, 0)
+	for _, c := range jqlAnd.Split(orderBy.ReplaceAllString(jql, ""), -1) {
+		if m := keyIn.FindStringSubmatch(c); m != nil {
+			keys := make(map[string]bool)
+			for _, key := range strings.Split(m[1], ",") {
+				key = s.current(strings.Trim(strings.TrimSpace(key), jqlQuote))
+				s.mu.Lock()
+				_, ok := s.issues[key]
+				s.mu.Unlock()
+				if strict && !ok {
+					return nil, fmt.Errorf("fake: an issue with key '%s' does not exist for field 'key'", key)
+				}
+				keys[key] = true
+			}
+			filters = append(filters, func(issue atlassian.JIRAIssue) bool { return keys[issue.Key] })
+			continue
+		}
+		m := clause.FindStringSubmatch(c)
+		if m == nil {
+			return nil, unsupported
+		}
+		field, negate, value := strings.ToLower(m[1]), m[2] == "!=", strings.Trim(m[3], jqlQuote)
+		var filter func(atlassian.JIRAIssue) bool
+		switch field {
+		case "project":
+			filter = func(issue atlassian.JIRAIssue) bool { return strings.EqualFold(issue.Fields.Project.Key, value) }
+		case "status":
+			filter = func(issue atlassian.JIRAIssue) bool { return strings.EqualFold(issue.Fields.Status.Name, value) }
+		case "issuetype":
+			filter = func(issue atlassian.JIRAIssue) bool { return strings.EqualFold(issue.Fields.IssueType.Name, value) }
+		case "labels":
+			filter = func(issue atlassian.JIRAIssue) bool {
+				for _, label := range issue.Fields.Labels {
+					if strings.EqualFold(label, value) {
+						return true
+					}
+				}
+				return false
+			}
+		case "assignee":
+			if !strings.EqualFold(value, "currentUser()") {
+				return nil, unsupported
+			}
+			filter = func(issue atlassian.JIRAIssue) bool {
+				return issue.Fields.Assignee != nil && issue.Fields.Assignee.AccountID == s.myself.AccountID
+			}
+		case "resolution":
+			if !strings.EqualFold(value, "Unresolved") {
+				return nil, unsupported
+			}
+			filter = func(issue atlassian.JIRAIssue) bool {
+				return issue.Fields.Status.StatusCategory.Key != "done" && !strings.EqualFold(issue.Fields.Status.Name, "Done")
+			}
+		}
+		if negate {
+			positive := filter
+			filter = func(issue atlassian.JIRAIssue) bool { return !positive(issue) }
+		}
+		filters = append(filters, filter)
+	}
+	return func(issue atlassian.JIRAIssue) bool {
+		for _, filter := range filters {
+			if !filter(issue) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// current returns the key an issue has now, following Moved.
+func (s *Server) current(key string) string {
+	if moved, ok := s.moved[key]; ok {
+		return moved
+	}
+	return key
+}
+
+// bounds returns the start and end of the page of n items starting at offset.
+func bounds(n, offset, size int) (int, int) {
+	if offset > n {
+		offset = n
+	}
+	end := offset + size
+	if end > n {
+		end = n
+	}
+	return offset, end
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeTempoError writes an error the way Tempo does, as an errors array.
+func writeTempoError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"errors": []map[string]string{{"message": message}},
+	})
+}
+
+func writeError(w http.ResponseWriter, status int, body string) {
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(body))
+}
@@ -0,0 +1,70 @@
+package fake
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/go-ini/ini"
+	"github.com/josh5276/halp/core"
+	"github.com/josh5276/halp/core/keyring"
+	"github.com/josh5276/halp/shared/atlassian"
+)
+
+// SharedFixture returns the path of the fixture most tests start from, the
+// issues and worklogs of the fake user. Tests that need more keep a fixture of
+// their own under their testdata.
+func SharedFixture() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "testdata", "fixture.json")
+}
+
+// Start starts a server seeded with the fixture at path. Close it when done.
+func Start(t *testing.T, path string) *Server {
+	t.Helper()
+	f, err := LoadFixture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewServer(f)
+}
+
+// Settings loads the settings of a test from the contents of a settings.ini.
+func Settings(t *testing.T, data string) keyring.Settings {
+	t.Helper()
+	file, err := ini.InsensitiveLoad([]byte(data))
+	if err != nil {
+		t.Fatalf("ERROR: ini.Load:%s", err)
+	}
+	return keyring.Settings{File: file}
+}
+
+// Run runs the halp command at path, e.g. "jira worklog", registered by plugin
+// under the groups leading up to it, with the client of env pointed at the server.
+// Stdin defaults to an empty reader and stdout and stderr to a buffer, whose
+// contents are returned.
+func Run(t *testing.T, srv *Server, plugin core.Register, path string, env core.Env, args ...string) (string, error) {
+	t.Helper()
+	env.Connect = func(*keyring.Settings) (*atlassian.Client, error) { return srv.NewClient() }
+
+	names := strings.Fields(path)
+	for i := len(names) - 2; i >= 0; i-- {
+		plugin = core.Group(names[i], "test "+names[i], plugin)
+	}
+	var out bytes.Buffer
+	if env.Stdin == nil {
+		env.Stdin = strings.NewReader("")
+	}
+	if env.Stdout == nil {
+		env.Stdout = &out
+	}
+	if env.Stderr == nil {
+		env.Stderr = &out
+	}
+	p := core.NewParser(plugin)
+	err := p.Execute(context.Background(), append(append([]string{core.AppName}, names...), args...), env)
+	return out.String(), err
+}
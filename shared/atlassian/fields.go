@@ -0,0 +1,378 @@
+package atlassian
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayout is the layout of JIRA date fields.
+const dateLayout = "2006-01-02"
+
+// createMetaPage is the number of issue types or fields asked for per page of the
+// per-project create metadata.
+const createMetaPage = 50
+
+// CreateMeta : Method used to fetch the fields issues are created with, per project and
+// issue type. Leave project or issueType empty to fetch every project or issue type.
+// JIRA Cloud, where the search is the enhanced one, has retired the expanded createmeta
+// so its metadata is fetched page by page from the per-project endpoints instead.
+func (c *Client) CreateMeta(ctx context.Context, project, issueType string) (CreateMeta, error) {
+	var meta CreateMeta
+	ctx, cancel := context.WithTimeout(ctx, time.Second*60)
+	defer cancel()
+
+	if c.search == SearchEnhanced {
+		return c.createMetaPaged(ctx, project, issueType)
+	}
+	query := url.Values{}
+	query.Set("expand", "projects.issuetypes.fields")
+	if project != "" {
+		query.Set("projectKeys", strings.ToUpper(project))
+	}
+	if issueType != "" {
+		query.Set("issuetypeNames", issueType)
+	}
+	err := c.do(ctx, request{
+		name:   "jira.CreateMeta",
+		method: http.MethodGet,
+		url:    c.jiraAPI + "/issue/createmeta?" + query.Encode(),
+		auth:   c.jiraAuth,
+	}, &meta)
+	return meta, err
+}
+
+// createMetaPaged : Fetches the create metadata from /issue/createmeta/{project}/issuetypes
+// and the fields of each issue type from /issue/createmeta/{project}/issuetypes/{id}.
+// Projects that do not exist are left out, like the expanded createmeta does.
+func (c *Client) createMetaPaged(ctx context.Context, project, issueType string) (CreateMeta, error) {
+	var meta CreateMeta
+	projects := []ProjectMeta{{Key: strings.ToUpper(project)}}
+	if project == "" {
+		var err error
+		if projects, err = c.projects(ctx); err != nil {
+			return meta, err
+		}
+	}
+	for _, p := range projects {
+		base := "/issue/createmeta/" + url.PathEscape(p.Key) + "/issuetypes"
+		err := c.createMetaPages(ctx, base, func(page CreateMetaPage) int {
+			for _, t := range page.IssueTypes {
+				if issueType == "" || strings.EqualFold(t.Name, issueType) {
+					p.IssueTypes = append(p.IssueTypes, t)
+				}
+			}
+			return len(page.IssueTypes)
+		})
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return meta, err
+		}
+		for i := range p.IssueTypes {
+			t := &p.IssueTypes[i]
+			t.Fields = make(map[string]FieldMeta)
+			err := c.createMetaPages(ctx, base+"/"+url.PathEscape(t.ID), func(page CreateMetaPage) int {
+				for _, f := range page.Fields {
+					t.Fields[f.FieldID] = f
+				}
+				return len(page.Fields)
+			})
+			if err != nil {
+				return meta, err
+			}
+		}
+		meta.Projects = append(meta.Projects, p)
+	}
+	return meta, nil
+}
+
+// createMetaPages : Follows the pages of a per-project create metadata endpoint, add
+// takes in each page and returns how many items it held.
+func (c *Client) createMetaPages(ctx context.Context, path string, add func(CreateMetaPage) int) error {
+	for startAt := 0; ; {
+		var page CreateMetaPage
+		err := c.do(ctx, request{
+			name:   "jira.CreateMeta",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("%s%s?startAt=%d&maxResults=%d", c.jiraAPI, path, startAt, createMetaPage),
+			auth:   c.jiraAuth,
+		}, &page)
+		if err != nil {
+			return err
+		}
+		n := add(page)
+		if startAt += n; n == 0 || startAt >= page.Total {
+			return nil
+		}
+	}
+}
+
+// projects : Lists every project of JIRA Cloud the user can see, page by page.
+func (c *Client) projects(ctx context.Context) ([]ProjectMeta, error) {
+	projects := make([]ProjectMeta, 0)
+	for {
+		var page ProjectPage
+		err := c.do(ctx, request{
+			name:   "jira.Projects",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("%s/project/search?startAt=%d&maxResults=%d", c.jiraAPI, len(projects), createMetaPage),
+			auth:   c.jiraAuth,
+		}, &page)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, page.Values...)
+		if page.IsLast || len(page.Values) == 0 {
+			return projects, nil
+		}
+	}
+}
+
+// IssueType : Finds the issue type of a project in the create metadata, ignoring case.
+func (m CreateMeta) IssueType(project, issueType string) (IssueTypeMeta, error) {
+	for _, p := range m.Projects {
+		if !strings.EqualFold(p.Key, project) {
+			continue
+		}
+		names := make([]string, 0, len(p.IssueTypes))
+		for _, t := range p.IssueTypes {
+			if strings.EqualFold(t.Name, issueType) {
+				return t, nil
+			}
+			names = append(names, t.Name)
+		}
+		return IssueTypeMeta{}, fmt.Errorf("jira.CreateMeta:%s has no issue type %q, use one of %s",
+			p.Key, issueType, strings.Join(names, ", "))
+	}
+	return IssueTypeMeta{}, fmt.Errorf("jira.CreateMeta:no project %q you can create issues in", project)
+}
+
+// Field : Finds a field of the issue type by its ID, e.g. customfield_10010, or by its
+// name, ignoring case. Names shared by several fields have to be given by ID instead.
+func (t IssueTypeMeta) Field(name string) (string, FieldMeta, error) {
+	if f, ok := t.Fields[name]; ok {
+		return name, f, nil
+	}
+	matches := make([]string, 0)
+	for id, f := range t.Fields {
+		if strings.EqualFold(f.Name, name) || strings.EqualFold(id, name) {
+			matches = append(matches, id)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", FieldMeta{}, fmt.Errorf("jira.Field:%s has no field %q", t.Name, name)
+	case 1:
+		return matches[0], t.Fields[matches[0]], nil
+	}
+	sort.Strings(matches)
+	return "", FieldMeta{}, fmt.Errorf("jira.Field:%q is ambiguous on %s, use one of %s",
+		name, t.Name, strings.Join(matches, ", "))
+}
+
+// Value : Converts a value written on the command line to the JSON JIRA expects for
+// the field, e.g. {"value": "ACME"} for a select list. Arrays take comma separated values.
+func (f FieldMeta) Value(raw string) (interface{}, error) {
+	if f.Schema.Type != "array" {
+		return f.item(f.Schema.Type, strings.TrimSpace(raw))
+	}
+	values := make([]interface{}, 0)
+	for _, v := range strings.Split(raw, ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		item, err := f.item(f.Schema.Items, v)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, item)
+	}
+	return values, nil
+}
+
+// item converts a single value of the schema type kind.
+func (f FieldMeta) item(kind, raw string) (interface{}, error) {
+	switch kind {
+	case "number":
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("jira.Field:%s expects a number, got %q", f.Name, raw)
+		}
+		return n, nil
+	case "date":
+		if _, err := time.Parse(dateLayout, raw); err != nil {
+			return nil, fmt.Errorf("jira.Field:%s expects a %s date, got %q", f.Name, dateLayout, raw)
+		}
+		return raw, nil
+	case "datetime":
+		if t, err := time.Parse(dateLayout, raw); err == nil {
+			return t.Format(jiraTimeLayout), nil
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return nil, fmt.Errorf("jira.Field:%s expects a %s date or an RFC 3339 time, got %q", f.Name, dateLayout, raw)
+		}
+		return t.Format(jiraTimeLayout), nil
+	case "user":
+		return map[string]string{"accountId": raw}, nil
+	case "option":
+		v, err := f.allowed(raw)
+		return map[string]string{"value": v}, err
+	case "priority", "component", "version", "resolution", "issuetype":
+		v, err := f.allowed(raw)
+		return map[string]string{"name": v}, err
+	case "project":
+		return map[string]string{"key": strings.ToUpper(raw)}, nil
+	}
+	return raw, nil
+}
+
+// allowed checks a value against the allowed values of the field, ignoring case,
+// and returns it the way JIRA spells it.
+func (f FieldMeta) allowed(raw string) (string, error) {
+	if len(f.AllowedValues) == 0 {
+		return raw, nil
+	}
+	names := make([]string, 0, len(f.AllowedValues))
+	for _, v := range f.AllowedValues {
+		name := v.Label()
+		if strings.EqualFold(name, raw) {
+			return name, nil
+		}
+		names = append(names, name)
+	}
+	return "", fmt.Errorf("jira.Field:%q is not allowed for %s, use one of %s", raw, f.Name, strings.Join(names, ", "))
+}
+
+// Label : The value as it is shown in JIRA, the value of an option or the name of anything else.
+func (v AllowedValue) Label() string {
+	if v.Value != "" {
+		return v.Value
+	}
+	return v.Name
+}
+
+// MarshalJSON : Encodes the fields along with the Custom ones.
+func (f IssueField) MarshalJSON() ([]byte, error) {
+	type plain IssueField
+	b, err := json.Marshal(plain(f))
+	if err != nil || len(f.Custom) == 0 {
+		return b, err
+	}
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	for id, v := range f.Custom {
+		fields[id] = v
+	}
+	return json.Marshal(fields)
+}
+
+// UnmarshalJSON : Decodes the fields, keeping the custom ones in Custom.
+func (f *IssueField) UnmarshalJSON(b []byte) error {
+	type plain IssueField
+	if err := json.Unmarshal(b, (*plain)(f)); err != nil {
+		return err
+	}
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	for id, v := range fields {
+		if strings.HasPrefix(id, "customfield_") {
+			if f.Custom == nil {
+				f.Custom = make(map[string]interface{})
+			}
+			f.Custom[id] = v
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON : Decodes the issue, keeping the custom fields that have a value in Custom.
+func (i *IssueDetail) UnmarshalJSON(b []byte) error {
+	type plain IssueDetail
+	if err := json.Unmarshal(b, (*plain)(i)); err != nil {
+		return err
+	}
+	var issue struct {
+		Fields map[string]json.RawMessage `json:"fields"`
+	}
+	if err := json.Unmarshal(b, &issue); err != nil {
+		return err
+	}
+	for id, v := range issue.Fields {
+		if !strings.HasPrefix(id, "customfield_") || FieldText(v) == "" {
+			continue
+		}
+		if i.Custom == nil {
+			i.Custom = make(map[string]json.RawMessage)
+		}
+		i.Custom[id] = v
+	}
+	return nil
+}
+
+// FieldText : The value of a field as text, e.g. ACME for the {"value": "ACME"} of a select
+// list. Lists are joined with commas and documents converted like descriptions, see Text.
+func FieldText(raw json.RawMessage) string {
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return ""
+	}
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return Text(raw)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		var items []json.RawMessage
+		_ = json.Unmarshal(raw, &items)
+		values := make([]string, 0, len(items))
+		for _, item := range items {
+			if text := FieldText(item); text != "" {
+				values = append(values, text)
+			}
+		}
+		return strings.Join(values, ", ")
+	case map[string]interface{}:
+		if v["type"] == "doc" {
+			return Text(raw)
+		}
+		var value struct {
+			Value       string          `json:"value"`
+			Name        string          `json:"name"`
+			DisplayName string          `json:"displayName"`
+			Key         string          `json:"key"`
+			Child       json.RawMessage `json:"child"`
+		}
+		_ = json.Unmarshal(raw, &value)
+		text := value.Value
+		for _, s := range []string{value.DisplayName, value.Name, value.Key} {
+			if text == "" {
+				text = s
+			}
+		}
+		// The option picked in a cascading select comes with the one under it.
+		if child := FieldText(value.Child); child != "" {
+			text += " / " + child
+		}
+		return text
+	}
+	return ""
+}
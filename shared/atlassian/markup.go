@@ -0,0 +1,275 @@
+package atlassian
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// adfNode : A node of an Atlassian Document Format document, the rich text JIRA
+// API version 3 returns for descriptions and comments.
+type adfNode struct {
+	Type    string                 `json:"type"`
+	Text    string                 `json:"text"`
+	Attrs   map[string]interface{} `json:"attrs"`
+	Content []adfNode              `json:"content"`
+	Marks   []struct {
+		Type  string                 `json:"type"`
+		Attrs map[string]interface{} `json:"attrs"`
+	} `json:"marks"`
+}
+
+var (
+	wikiHeading = regexp.MustCompile(`^h([1-6])\.\s+(.*)$`)
+	wikiList    = regexp.MustCompile(`^([*#-]+)\s+(.*)$`)
+	wikiBlock   = regexp.MustCompile(`^\{(code|noformat|quote|panel)(:[^}]*)?\}(.*)$`)
+	wikiLink    = regexp.MustCompile(`\[([^\]|]*)\|([^\]]+)\]`)
+	wikiURL     = regexp.MustCompile(`\[((?:https?|mailto):[^\]]+)\]`)
+	wikiMention = regexp.MustCompile(`\[~(?:accountid:)?([^\]]+)\]`)
+	wikiMono    = regexp.MustCompile(`\{\{(.+?)\}\}`)
+	wikiStrong  = regexp.MustCompile(`(^|\W)\*(\S(?:.*?\S)?)\*(\W|$)`)
+	wikiEmph    = regexp.MustCompile(`(^|\W)_(\S(?:.*?\S)?)_(\W|$)`)
+	wikiColor   = regexp.MustCompile(`\{color(:[^}]*)?\}`)
+
+	// adfParagraph separates the paragraphs of plain text, blank lines.
+	adfParagraph = regexp.MustCompile(`\n[ \t]*\n\s*`)
+)
+
+// Text : Renders a description or comment body as plain terminal text, whether JIRA
+// returned it as wiki markup (a JSON string) or as an ADF document (a JSON object).
+func Text(raw json.RawMessage) string {
+	var wiki string
+	if err := json.Unmarshal(raw, &wiki); err == nil {
+		return WikiText(wiki)
+	}
+	var doc adfNode
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(doc.block(""))
+}
+
+// ADF : Wraps plain text in the minimal Atlassian Document Format document JIRA API
+// version 3 takes for descriptions, a paragraph per block of lines with hard breaks
+// between the lines.
+func ADF(text string) map[string]interface{} {
+	paragraphs := make([]interface{}, 0)
+	for _, block := range adfParagraph.Split(strings.TrimSpace(strings.Replace(text, "\r\n", "\n", -1)), -1) {
+		content := make([]interface{}, 0)
+		for i, line := range strings.Split(block, "\n") {
+			if i > 0 {
+				content = append(content, map[string]interface{}{"type": "hardBreak"})
+			}
+			// ADF rejects empty text nodes.
+			if line != "" {
+				content = append(content, map[string]interface{}{"type": "text", "text": line})
+			}
+		}
+		if len(content) > 0 {
+			paragraphs = append(paragraphs, map[string]interface{}{"type": "paragraph", "content": content})
+		}
+	}
+	return map[string]interface{}{"type": "doc", "version": 1, "content": paragraphs}
+}
+
+// WikiText : Renders JIRA wiki markup as plain terminal text: headings are underlined,
+// lists indented, code and quotes set apart, and links written out with their address.
+func WikiText(s string) string {
+	var (
+		out     []string
+		block   string
+		numbers = make(map[int]int)
+	)
+	for _, line := range strings.Split(strings.Replace(s, "\r\n", "\n", -1), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if m := wikiBlock.FindStringSubmatch(trimmed); m != nil && (block == "" || block == m[1]) {
+			if block == "" {
+				block = m[1]
+			} else {
+				block = ""
+			}
+			if rest := strings.TrimSpace(m[3]); rest != "" {
+				out = append(out, wikiBlockLine(block, rest))
+			}
+			continue
+		}
+		if block != "" {
+			out = append(out, wikiBlockLine(block, line))
+			continue
+		}
+
+		if m := wikiList.FindStringSubmatch(trimmed); m != nil && !(m[1] == "-" && strings.HasPrefix(trimmed, "----")) {
+			depth := len(m[1])
+			bullet := "•"
+			if strings.HasSuffix(m[1], "#") {
+				numbers[depth]++
+				bullet = strconv.Itoa(numbers[depth]) + "."
+			}
+			for d := range numbers {
+				if d > depth {
+					delete(numbers, d)
+				}
+			}
+			out = append(out, strings.Repeat("  ", depth-1)+bullet+" "+wikiInline(m[2]))
+			continue
+		}
+		numbers = make(map[int]int)
+
+		switch m := wikiHeading.FindStringSubmatch(trimmed); {
+		case m != nil:
+			out = append(out, underline(wikiInline(m[2])))
+		case strings.HasPrefix(trimmed, "bq. "):
+			out = append(out, "> "+wikiInline(strings.TrimPrefix(trimmed, "bq. ")))
+		case strings.HasPrefix(trimmed, "----"):
+			out = append(out, "----")
+		case strings.HasPrefix(trimmed, "|"):
+			cells := strings.Split(strings.Trim(strings.Replace(trimmed, "||", "|", -1), "|"), "|")
+			for i, cell := range cells {
+				cells[i] = wikiInline(strings.TrimSpace(cell))
+			}
+			out = append(out, strings.Join(cells, " | "))
+		default:
+			out = append(out, wikiInline(line))
+		}
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// wikiBlockLine lays out a line inside a {code}, {noformat}, {quote} or {panel} block.
+func wikiBlockLine(block, line string) string {
+	switch block {
+	case "code", "noformat":
+		return "    " + line
+	case "quote":
+		return "> " + wikiInline(strings.TrimSpace(line))
+	}
+	return wikiInline(line)
+}
+
+// wikiInline renders the inline markup of a line.
+func wikiInline(s string) string {
+	s = strings.Replace(s, `\\`, "\n", -1)
+	s = wikiColor.ReplaceAllString(s, "")
+	s = wikiMention.ReplaceAllString(s, "@$1")
+	s = wikiLink.ReplaceAllStringFunc(s, func(link string) string {
+		m := wikiLink.FindStringSubmatch(link)
+		if m[1] == "" || m[1] == m[2] {
+			return m[2]
+		}
+		return fmt.Sprintf("%s (%s)", m[1], m[2])
+	})
+	s = wikiURL.ReplaceAllString(s, "$1")
+	s = wikiMono.ReplaceAllString(s, "`$1`")
+	s = wikiStrong.ReplaceAllString(s, "$1$2$3")
+	s = wikiEmph.ReplaceAllString(s, "$1$2$3")
+	return s
+}
+
+// underline sets a heading apart with a line of dashes.
+func underline(s string) string {
+	return s + "\n" + strings.Repeat("-", len([]rune(s)))
+}
+
+// block renders an ADF block node and its children, prefixing every line with indent.
+func (n adfNode) block(indent string) string {
+	switch n.Type {
+	case "paragraph":
+		return indent + strings.Replace(n.inline(), "\n", "\n"+indent, -1)
+	case "heading":
+		return indent + strings.Replace(underline(n.inline()), "\n", "\n"+indent, -1)
+	case "bulletList", "orderedList":
+		start := 1
+		if order, ok := n.Attrs["order"].(float64); ok {
+			start = int(order)
+		}
+		items := make([]string, 0, len(n.Content))
+		for i, item := range n.Content {
+			bullet := "• "
+			if n.Type == "orderedList" {
+				bullet = strconv.Itoa(start+i) + ". "
+			}
+			body := strings.TrimLeft(item.blocks(indent+"  ", "\n"), " ")
+			items = append(items, indent+bullet+body)
+		}
+		return strings.Join(items, "\n")
+	case "codeBlock":
+		lines := strings.Split(n.inline(), "\n")
+		for i, line := range lines {
+			lines[i] = indent + "    " + line
+		}
+		return strings.Join(lines, "\n")
+	case "blockquote":
+		return n.blocks(indent+"> ", "\n"+indent+">\n")
+	case "rule":
+		return indent + "----"
+	case "table":
+		return n.blocks(indent, "\n")
+	case "tableRow":
+		cells := make([]string, 0, len(n.Content))
+		for _, cell := range n.Content {
+			cells = append(cells, strings.TrimSpace(cell.blocks("", " ")))
+		}
+		return indent + strings.Join(cells, " | ")
+	case "mediaSingle", "mediaGroup":
+		return indent + "[attachment]"
+	}
+	if len(n.Content) > 0 && n.Content[0].Type != "text" {
+		return n.blocks(indent, "\n\n")
+	}
+	return indent + n.inline()
+}
+
+// blocks renders the children of a node one after the other.
+func (n adfNode) blocks(indent, sep string) string {
+	parts := make([]string, 0, len(n.Content))
+	for _, child := range n.Content {
+		parts = append(parts, child.block(indent))
+	}
+	return strings.Join(parts, sep)
+}
+
+// inline renders the text of a node and its inline children.
+func (n adfNode) inline() string {
+	switch n.Type {
+	case "text":
+		text := n.Text
+		for _, mark := range n.Marks {
+			switch mark.Type {
+			case "code":
+				text = "`" + text + "`"
+			case "link":
+				if href, _ := mark.Attrs["href"].(string); href != "" && href != text {
+					text = fmt.Sprintf("%s (%s)", text, href)
+				}
+			}
+		}
+		return text
+	case "hardBreak":
+		return "\n"
+	case "mention", "emoji", "status":
+		for _, key := range []string{"text", "shortName"} {
+			if v, ok := n.Attrs[key].(string); ok && v != "" {
+				return v
+			}
+		}
+		return ""
+	case "inlineCard", "blockCard":
+		v, _ := n.Attrs["url"].(string)
+		return v
+	case "date":
+		if ts, ok := n.Attrs["timestamp"].(string); ok {
+			if ms, err := strconv.ParseInt(ts, 10, 64); err == nil {
+				return time.Unix(ms/1000, 0).UTC().Format(dateLayout)
+			}
+		}
+		return ""
+	}
+	var b strings.Builder
+	for _, child := range n.Content {
+		b.WriteString(child.inline())
+	}
+	return b.String()
+}
@@ -0,0 +1,81 @@
+package atlassian_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/josh5276/halp/shared/atlassian"
+)
+
+func TestText(t *testing.T) {
+	wiki, _ := json.Marshal("h2. Plan\n" +
+		"Upgrade *core* switches, see [the runbook|https://wiki.example.com/runbook] and {{show version}}.\n" +
+		"# Drain traffic\n" +
+		"## Check [~accountid:abc123]\n" +
+		"# Reload\n" +
+		"{code:bash}\n" +
+		"reload in 5\n" +
+		"{code}\n" +
+		"bq. Maintenance window only")
+	adf := json.RawMessage(`{"type": "doc", "version": 1, "content": [
+		{"type": "heading", "attrs": {"level": 2}, "content": [{"type": "text", "text": "Plan"}]},
+		{"type": "paragraph", "content": [
+			{"type": "text", "text": "Upgrade core switches, see "},
+			{"type": "text", "text": "the runbook", "marks": [{"type": "link", "attrs": {"href": "https://wiki.example.com/runbook"}}]},
+			{"type": "text", "text": " and "},
+			{"type": "text", "text": "show version", "marks": [{"type": "code"}]},
+			{"type": "text", "text": "."}
+		]},
+		{"type": "orderedList", "content": [
+			{"type": "listItem", "content": [
+				{"type": "paragraph", "content": [{"type": "text", "text": "Drain traffic"}]},
+				{"type": "orderedList", "content": [{"type": "listItem", "content": [
+					{"type": "paragraph", "content": [{"type": "text", "text": "Check "}, {"type": "mention", "attrs": {"id": "abc123", "text": "@abc123"}}]}
+				]}]}
+			]},
+			{"type": "listItem", "content": [{"type": "paragraph", "content": [{"type": "text", "text": "Reload"}]}]}
+		]},
+		{"type": "codeBlock", "attrs": {"language": "bash"}, "content": [{"type": "text", "text": "reload in 5"}]},
+		{"type": "blockquote", "content": [{"type": "paragraph", "content": [{"type": "text", "text": "Maintenance window only"}]}]}
+	]}`)
+
+	expected := "Plan\n" +
+		"----\n" +
+		"Upgrade core switches, see the runbook (https://wiki.example.com/runbook) and `show version`.\n" +
+		"1. Drain traffic\n" +
+		"  1. Check @abc123\n" +
+		"2. Reload\n" +
+		"    reload in 5\n" +
+		"> Maintenance window only"
+	if text := atlassian.Text(wiki); text != expected {
+		t.Fatalf("ERROR: wiki markup rendered as\n%s\nexpected\n%s", text, expected)
+	}
+	// ADF separates its blocks with a blank line.
+	if text := atlassian.Text(adf); text != "Plan\n----\n\n"+
+		"Upgrade core switches, see the runbook (https://wiki.example.com/runbook) and `show version`.\n\n"+
+		"1. Drain traffic\n  1. Check @abc123\n2. Reload\n\n    reload in 5\n\n> Maintenance window only" {
+		t.Fatalf("ERROR: ADF rendered as\n%s", text)
+	}
+	if text := atlassian.Text(json.RawMessage("null")); text != "" {
+		t.Fatalf("ERROR: expected no description to render empty, got %q", text)
+	}
+	t.Log("SUCCESS: rendered wiki markup and ADF")
+}
+
+func TestFieldText(t *testing.T) {
+	for raw, expected := range map[string]string{
+		`null`:                         "",
+		`"ACME-001"`:                   "ACME-001",
+		`5.5`:                          "5.5",
+		`{"id": "1", "value": "ACME"}`: "ACME",
+		`[{"value": "DFW3"}, null, {"value": "IAD2"}]`:                                                                      "DFW3, IAD2",
+		`{"value": "US", "child": {"value": "Texas"}}`:                                                                      "US / Texas",
+		`{"accountId": "abc123", "displayName": "Fake User"}`:                                                               "Fake User",
+		`{"type": "doc", "version": 1, "content": [{"type": "paragraph", "content": [{"type": "text", "text": "Notes"}]}]}`: "Notes",
+	} {
+		if text := atlassian.FieldText(json.RawMessage(raw)); text != expected {
+			t.Fatalf("ERROR: %s rendered as %q, expected %q", raw, text, expected)
+		}
+	}
+	t.Log("SUCCESS: rendered the field values")
+}
@@ -1,168 +1,483 @@
 package atlassian
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
-// WorkLogs : Method used to fetch workloads from the Tempo API endpoint.
-func (c *client) WorkLogs(to, from string) ([]Worklog, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*60)
+// IssueBatchSize is the number of issue keys JiraIssues puts in a single
+// search query, keeping the JQL and the URL carrying it a sane length.
+const IssueBatchSize = 50
+
+// jiraTimeLayout is the layout JIRA uses for timestamps such as created and updated.
+const jiraTimeLayout = "2006-01-02T15:04:05.000-0700"
+
+// issueFields limits the issue fields returned by JIRA to the ones in JIRAIssue.
+var issueFields = []string{
+	"summary",
+	"project",
+	"priority",
+	"status",
+	"assignee",
+	"issuetype",
+	"labels",
+	"created",
+	"updated",
+}
+
+// WorkLogs : Method used to fetch workloads from the Tempo API endpoint. The request
+// is bound to the context passed in so that it is abandoned when halp is interrupted.
+func (c *Client) WorkLogs(ctx context.Context, to, from string) ([]Worklog, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*60)
 	defer cancel()
 
-	returnData := make([]Worklog, 0)
-	url := fmt.Sprintf("https://api.tempo.io/core/3/worklogs?from=%s&to=%s", from, to)
+	query := url.Values{}
+	query.Set("from", from)
+	query.Set("to", to)
+	return c.worklogPages(ctx, "jira.WorkLogs", c.tempoAPI+"/worklogs?"+query.Encode())
+}
 
-	for {
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-		if err != nil {
-			return nil, err
-		}
+// IssueWorklogs : Method used to fetch every Tempo worklog of an issue, by anyone.
+func (c *Client) IssueWorklogs(ctx context.Context, issueKey string) ([]Worklog, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*60)
+	defer cancel()
 
-		// Setting the request header for CTK token auth
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.tempoToken))
+	return c.worklogPages(ctx, "jira.IssueWorklogs", fmt.Sprintf("%s/worklogs/issue/%s", c.tempoAPI, url.PathEscape(issueKey)))
+}
 
-		res, err := c.client.Do(req)
-		if res != nil {
-			// Note: We use a func to error check defer as opposed to using
-			// defer res.Body.Close(), which will never return an error.
-			defer func() {
-				if defErr := res.Body.Close(); defErr != nil {
-					err = fmt.Errorf("%s:%s", err, defErr)
-				}
-			}()
-		}
+// worklogPages : Fetches a list of worklogs starting at next, following the pages
+// until every worklog has been read.
+func (c *Client) worklogPages(ctx context.Context, name, next string) ([]Worklog, error) {
+	returnData := make([]Worklog, 0)
+	for next != "" {
+		var resp WorkLogResponse
+		err := c.do(ctx, request{name: name, method: http.MethodGet, url: next, auth: c.tempoAuth}, &resp)
 		if err != nil {
 			return nil, err
 		}
-		if res.StatusCode > http.StatusNoContent {
-			return nil, fmt.Errorf("jira.WorkLogs:%s", res.Status)
-		}
-
-		var resp = new(WorkLogResponse)
-		if err := json.NewDecoder(res.Body).Decode(resp); err != nil {
-			return nil, err
-		}
 		returnData = append(returnData, resp.Results...)
-		if resp.Metadata.Next == "" {
-			break
-		}
-		url = resp.Metadata.Next
+		next = resp.Metadata.Next
 	}
-
 	return returnData, nil
 }
 
 // JiraIssue : Method used to fetch a jira issue from Atlassian.
-func (c *client) JiraIssue(issueKey string) (JIRAIssue, error) {
+func (c *Client) JiraIssue(ctx context.Context, issueKey string) (JIRAIssue, error) {
 	var issue JIRAIssue
-	if _, ok := c.jiraIssues[issueKey]; ok {
-		return c.jiraIssues[issueKey], nil
+	if cached, ok := c.cachedIssue(issueKey); ok {
+		return cached, nil
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*60)
+	ctx, cancel := context.WithTimeout(ctx, time.Second*60)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(
-		ctx,
-		http.MethodGet,
-		fmt.Sprintf("https://%s/rest/api/2/issue/%s", c.instance, issueKey),
-		nil,
-	)
+	query := url.Values{}
+	query.Set("fields", strings.Join(issueFields, ","))
+	err := c.do(ctx, request{
+		name:   "jira.Issue",
+		method: http.MethodGet,
+		url:    fmt.Sprintf("%s/issue/%s?%s", c.jiraAPI, url.PathEscape(issueKey), query.Encode()),
+		auth:   c.jiraAuth,
+	}, &issue)
 	if err != nil {
 		return issue, err
 	}
+	c.cacheIssue(issueKey, issue)
+	return issue, nil
+}
 
-	// Setting the request header for CTK token auth
-	req.Header.Set("Content-Type", "application/json")
-	req.SetBasicAuth(c.jiraUser, c.jiraToken)
-
-	query := req.URL.Query()
-	limitFields := []string{
-		"summary",
-		"project",
-		"priority",
-		"status",
-		"assignee",
-		"created",
-		"updated",
-	}
-	query.Add("fields", strings.Join(limitFields, ","))
-	req.URL.RawQuery = query.Encode()
-
-	res, err := c.client.Do(req)
-	if res != nil {
-		// Note: We use a func to error check defer as opposed to using
-		// defer res.Body.Close(), which will never return an error.
-		defer func() {
-			if defErr := res.Body.Close(); defErr != nil {
-				err = fmt.Errorf("%s:%s", err, defErr)
+// JiraIssues : Method used to fetch many jira issues at once through the search API,
+// IssueBatchSize keys per query. Issues already cached are not requested again. The
+// issues are keyed by the keys asked for, even when they have since been moved to
+// another key. Keys that do not exist are left out of the result rather than failing
+// the whole batch.
+func (c *Client) JiraIssues(ctx context.Context, issueKeys []string) (map[string]JIRAIssue, error) {
+	issues := make(map[string]JIRAIssue, len(issueKeys))
+	missing := make([]string, 0)
+	seen := make(map[string]bool, len(issueKeys))
+	for _, key := range issueKeys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if cached, ok := c.cachedIssue(key); ok {
+			issues[key] = cached
+		} else {
+			missing = append(missing, key)
+		}
+	}
+
+	for start := 0; start < len(missing); start += IssueBatchSize {
+		end := start + IssueBatchSize
+		if end > len(missing) {
+			end = len(missing)
+		}
+		jql := fmt.Sprintf("key in (%s)", strings.Join(missing[start:end], ","))
+		// Unknown keys in a key in (...) clause are reported as warnings
+		// instead of rejecting the query. The enhanced search can't be lenient,
+		// a batch it rejects is looked up key by key below.
+		found, _, err := c.Search(ctx, jql, SearchOptions{Lenient: true})
+		var apiErr *APIError
+		if c.search == SearchEnhanced && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest {
+			found, err = nil, nil
+		}
+		if err != nil {
+			return issues, err
+		}
+		byKey := make(map[string]JIRAIssue, len(found))
+		for _, issue := range found {
+			byKey[issue.Key] = issue
+		}
+		for _, key := range missing[start:end] {
+			if issue, ok := byKey[key]; ok {
+				c.cacheIssue(key, issue)
+				issues[key] = issue
+				continue
+			}
+			// An issue that was moved or renamed comes back under its new key, which
+			// only a lookup of the old key maps back. Keys that do not exist at all
+			// end up here too and are left out.
+			issue, err := c.JiraIssue(ctx, key)
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+				continue
 			}
-		}()
+			if err != nil {
+				return issues, err
+			}
+			issues[key] = issue
+		}
 	}
-	if err != nil {
-		return issue, err
+	return issues, nil
+}
+
+// SearchOptions : Options for Search.
+type SearchOptions struct {
+	// Limit is the most issues read, 0 reads every page.
+	Limit int
+	// Lenient reports the unknown keys and fields of the JQL as warnings
+	// instead of rejecting the query. Only the legacy search can be lenient.
+	Lenient bool
+}
+
+// Search : Method used to run a JQL query against the search API, following the pages
+// until every matching issue, or Limit of them, has been read. The total is the
+// number of issues matching the query, which can be more than the ones returned.
+// The enhanced search doesn't count them, the total is -1 when Limit left some out.
+func (c *Client) Search(ctx context.Context, jql string, opts SearchOptions) ([]JIRAIssue, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*60)
+	defer cancel()
+
+	if c.search == SearchEnhanced {
+		return c.searchJQL(ctx, jql, opts)
 	}
+	returnData := make([]JIRAIssue, 0)
+	total := 0
+	for {
+		query := url.Values{}
+		query.Set("jql", jql)
+		query.Set("fields", strings.Join(issueFields, ","))
+		query.Set("startAt", strconv.Itoa(len(returnData)))
+		query.Set("maxResults", strconv.Itoa(searchPage(opts.Limit, len(returnData))))
+		if opts.Lenient {
+			query.Set("validateQuery", "warn")
+		}
 
-	if res.StatusCode > http.StatusNoContent {
-		return issue, fmt.Errorf("jira.Issue:%s", res.Status)
+		var resp SearchResponse
+		err := c.do(ctx, request{
+			name:   "jira.Search",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("%s/search?%s", c.jiraAPI, query.Encode()),
+			auth:   c.jiraAuth,
+		}, &resp)
+		if err != nil {
+			return nil, 0, err
+		}
+		total = resp.Total
+		returnData = append(returnData, resp.Issues...)
+		if len(resp.Issues) == 0 || len(returnData) >= resp.Total ||
+			(opts.Limit > 0 && len(returnData) >= opts.Limit) {
+			break
+		}
 	}
-	if err := json.NewDecoder(res.Body).Decode(&issue); err != nil {
-		return issue, err
+	return returnData, total, nil
+}
+
+// searchJQL : Runs a JQL query against the enhanced search of JIRA Cloud, following
+// the nextPageToken of each page.
+func (c *Client) searchJQL(ctx context.Context, jql string, opts SearchOptions) ([]JIRAIssue, int, error) {
+	returnData := make([]JIRAIssue, 0)
+	next := ""
+	for {
+		query := url.Values{}
+		query.Set("jql", jql)
+		query.Set("fields", strings.Join(issueFields, ","))
+		query.Set("maxResults", strconv.Itoa(searchPage(opts.Limit, len(returnData))))
+		if next != "" {
+			query.Set("nextPageToken", next)
+		}
+
+		var resp SearchJQLResponse
+		err := c.do(ctx, request{
+			name:   "jira.Search",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("%s/search/jql?%s", c.jiraAPI, query.Encode()),
+			auth:   c.jiraAuth,
+		}, &resp)
+		if err != nil {
+			return nil, 0, err
+		}
+		returnData = append(returnData, resp.Issues...)
+		next = resp.NextPageToken
+		if resp.IsLast || next == "" || len(resp.Issues) == 0 {
+			return returnData, len(returnData), nil
+		}
+		if opts.Limit > 0 && len(returnData) >= opts.Limit {
+			return returnData, -1, nil
+		}
+	}
+}
+
+// searchPage : The number of issues to ask for in the next page of a search, at
+// most IssueBatchSize and no more than are left to reach the limit.
+func searchPage(limit, read int) int {
+	if limit > 0 && limit-read < IssueBatchSize {
+		return limit - read
+	}
+	return IssueBatchSize
+}
+
+// cachedIssue : Looks up an issue fetched earlier by this client, or by an earlier
+// run when a persistent cache is set.
+func (c *Client) cachedIssue(issueKey string) (JIRAIssue, bool) {
+	c.mu.RLock()
+	issue, ok := c.jiraIssues[issueKey]
+	c.mu.RUnlock()
+	if ok || c.cache == nil {
+		return issue, ok
 	}
+	if !c.cache.Get(c.cacheBucket(), issueKey, &issue) {
+		return issue, false
+	}
+	c.mu.Lock()
 	c.jiraIssues[issueKey] = issue
-	return c.jiraIssues[issueKey], nil
+	c.mu.Unlock()
+	return issue, true
 }
 
-// NewIssue : Method used to create a new issue.
-func (c *client) NewIssue(newIssue IssueRequest) (IssueResponse, error) {
-	var returnData IssueResponse
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*60)
-	defer cancel()
+// cacheIssue : Stores a fetched issue so later lookups skip the request.
+func (c *Client) cacheIssue(issueKey string, issue JIRAIssue) {
+	c.mu.Lock()
+	c.jiraIssues[issueKey] = issue
+	c.mu.Unlock()
+	if c.cache == nil {
+		return
+	}
+	if err := c.cache.Put(c.cacheBucket(), issueKey, issue.UpdatedAt(), issue); err != nil {
+		logrus.Debugf("atlassian.cacheIssue:%s:%s", issueKey, err)
+	}
+}
 
-	js, err := json.Marshal(newIssue)
-	if err != nil {
-		return returnData, err
+// CachedIssueKeys : The keys of the issues kept in the persistent cache for this
+// instance, including the expired ones, e.g. to complete project keys offline.
+func (c *Client) CachedIssueKeys() []string {
+	if c.cache == nil {
+		return nil
 	}
+	return c.cache.Keys(c.cacheBucket())
+}
+
+// cacheBucket : Issues are cached per instance, as issue keys are only unique within one.
+func (c *Client) cacheBucket() string {
+	return "jira-issues/" + c.instance
+}
 
-	req, err := http.NewRequestWithContext(
-		ctx,
-		http.MethodPost,
-		fmt.Sprintf("https://%s/rest/api/2/issue/", c.instance),
-		bytes.NewReader(js),
-	)
+// UpdatedAt : Parses the time the issue was last updated, or the zero time when
+// the updated field was not fetched.
+func (i JIRAIssue) UpdatedAt() time.Time {
+	return ParseTime(i.Fields.Updated)
+}
+
+// ParseTime : Parses a JIRA timestamp such as the created time of an issue or
+// comment, or returns the zero time when it is empty or invalid.
+func ParseTime(s string) time.Time {
+	t, err := time.Parse(jiraTimeLayout, s)
 	if err != nil {
-		return returnData, err
+		return time.Time{}
 	}
+	return t
+}
 
-	// Setting the request header for CTK token auth
-	req.Header.Set("Content-Type", "application/json")
-	req.SetBasicAuth(c.jiraUser, c.jiraToken)
+// IssueDetail : Method used to fetch an issue with its description, comments, sub-tasks,
+// links and custom fields, along with the names of the fields. Unlike JiraIssue it always
+// asks JIRA, as the details are not cached.
+func (c *Client) IssueDetail(ctx context.Context, issueKey string) (IssueDetail, error) {
+	var issue IssueDetail
+	ctx, cancel := context.WithTimeout(ctx, time.Second*60)
+	defer cancel()
 
-	res, err := c.client.Do(req)
-	if res != nil {
-		// Note: We use a func to error check defer as opposed to using
-		// defer res.Body.Close(), which will never return an error.
-		defer func() {
-			if defErr := res.Body.Close(); defErr != nil {
-				err = fmt.Errorf("%s:%s", err, defErr)
-			}
-		}()
+	query := url.Values{}
+	query.Set("fields", strings.Join(append(issueFields[:len(issueFields):len(issueFields)], "reporter", "description", "parent",
+		"subtasks", "issuelinks", "comment", "*navigable"), ","))
+	query.Set("expand", "names")
+	err := c.do(ctx, request{
+		name:   "jira.IssueDetail",
+		method: http.MethodGet,
+		url:    fmt.Sprintf("%s/issue/%s?%s", c.jiraAPI, url.PathEscape(issueKey), query.Encode()),
+		auth:   c.jiraAuth,
+	}, &issue)
+	return issue, err
+}
+
+// NewIssue : Method used to create a new issue. Creating an issue is not idempotent,
+// so the request is only retried when JIRA turned it away with a 429. On API version 3
+// the description is sent as an ADF document.
+func (c *Client) NewIssue(ctx context.Context, newIssue IssueRequest) (IssueResponse, error) {
+	var returnData IssueResponse
+	ctx, cancel := context.WithTimeout(ctx, time.Second*60)
+	defer cancel()
+
+	if c.adf && newIssue.Fields.Description != "" {
+		custom := map[string]interface{}{"description": ADF(newIssue.Fields.Description)}
+		for id, v := range newIssue.Fields.Custom {
+			custom[id] = v
+		}
+		newIssue.Fields.Custom = custom
+	}
+
+	err := c.do(ctx, request{
+		name:   "jira.NewIssue",
+		method: http.MethodPost,
+		url:    c.jiraAPI + "/issue/",
+		body:   newIssue,
+		auth:   c.jiraAuth,
+	}, &returnData)
+	return returnData, err
+}
+
+// Myself : Method used to fetch the JIRA user the client is authenticated as, whose
+// account ID Tempo expects as the author of new worklogs.
+func (c *Client) Myself(ctx context.Context) (User, error) {
+	var user User
+	ctx, cancel := context.WithTimeout(ctx, time.Second*60)
+	defer cancel()
+
+	err := c.do(ctx, request{
+		name:   "jira.Myself",
+		method: http.MethodGet,
+		url:    c.jiraAPI + "/myself",
+		auth:   c.jiraAuth,
+	}, &user)
+	return user, err
+}
+
+// AccountID : The JIRA account ID new worklogs are logged as, from Options or else
+// looked up once with Myself.
+func (c *Client) AccountID(ctx context.Context) (string, error) {
+	c.mu.RLock()
+	accountID := c.accountID
+	c.mu.RUnlock()
+	if accountID != "" {
+		return accountID, nil
 	}
+	user, err := c.Myself(ctx)
 	if err != nil {
-		return returnData, err
+		return "", err
 	}
+	if user.AccountID == "" {
+		return "", fmt.Errorf("jira.Myself:no account ID returned for %s, set account_id under [atlassian]", c.jiraUser)
+	}
+	c.mu.Lock()
+	c.accountID = user.AccountID
+	c.mu.Unlock()
+	return user.AccountID, nil
+}
+
+// NewWorklog : Method used to log time to an issue in Tempo. Like NewIssue it is only
+// retried when Tempo turned the request away with a 429, so time is never logged twice.
+func (c *Client) NewWorklog(ctx context.Context, worklog WorklogRequest) (Worklog, error) {
+	var returnData Worklog
+	ctx, cancel := context.WithTimeout(ctx, time.Second*60)
+	defer cancel()
+
+	err := c.do(ctx, request{
+		name:   "jira.NewWorklog",
+		method: http.MethodPost,
+		url:    c.tempoAPI + "/worklogs",
+		body:   worklog,
+		auth:   c.tempoAuth,
+	}, &returnData)
+	return returnData, err
+}
+
+// Worklog : Method used to fetch a single Tempo worklog by its Tempo worklog ID.
+func (c *Client) Worklog(ctx context.Context, id int) (Worklog, error) {
+	var returnData Worklog
+	ctx, cancel := context.WithTimeout(ctx, time.Second*60)
+	defer cancel()
+
+	err := c.do(ctx, request{
+		name:   "jira.Worklog",
+		method: http.MethodGet,
+		url:    fmt.Sprintf("%s/worklogs/%d", c.tempoAPI, id),
+		auth:   c.tempoAuth,
+	}, &returnData)
+	return returnData, err
+}
+
+// UpdateWorklog : Method used to replace a Tempo worklog. Tempo expects every field of
+// the worklog, start from Worklog.Request to change only some of them.
+func (c *Client) UpdateWorklog(ctx context.Context, id int, worklog WorklogRequest) (Worklog, error) {
+	var returnData Worklog
+	ctx, cancel := context.WithTimeout(ctx, time.Second*60)
+	defer cancel()
 
-	if res.StatusCode > http.StatusNoContent {
-		return returnData, fmt.Errorf("jira.Issue:%s", res.Status)
+	err := c.do(ctx, request{
+		name:   "jira.UpdateWorklog",
+		method: http.MethodPut,
+		url:    fmt.Sprintf("%s/worklogs/%d", c.tempoAPI, id),
+		body:   worklog,
+		auth:   c.tempoAuth,
+	}, &returnData)
+	return returnData, err
+}
+
+// DeleteWorklog : Method used to delete a Tempo worklog.
+func (c *Client) DeleteWorklog(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*60)
+	defer cancel()
+
+	return c.do(ctx, request{
+		name:   "jira.DeleteWorklog",
+		method: http.MethodDelete,
+		url:    fmt.Sprintf("%s/worklogs/%d", c.tempoAPI, id),
+		auth:   c.tempoAuth,
+	}, nil)
+}
+
+// Request : The request recreating the worklog as it is, the starting point of an
+// update. The billable time is only kept when it differs from the time spent, so
+// that Tempo works it out again when the time spent changes.
+func (w Worklog) Request() WorklogRequest {
+	req := WorklogRequest{
+		IssueKey:         w.Issue.Key,
+		TimeSpentSeconds: w.TimeSpentSeconds,
+		StartDate:        w.StartDate,
+		StartTime:        w.StartTime,
+		Description:      w.Description,
+		AuthorAccountID:  w.Author.AccountID,
+		Attributes:       w.Attributes.Values,
 	}
-	if err := json.NewDecoder(res.Body).Decode(&returnData); err != nil {
-		return returnData, err
+	if w.BillableSeconds != w.TimeSpentSeconds {
+		billable := w.BillableSeconds
+		req.BillableSeconds = &billable
 	}
-	return returnData, nil
+	return req
 }
@@ -0,0 +1,376 @@
+package atlassian_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/josh5276/halp/shared/atlassian"
+	"github.com/josh5276/halp/shared/atlassian/fake"
+)
+
+func testServer(t *testing.T) (*fake.Server, *atlassian.Client) {
+	srv := fake.Start(t, fake.SharedFixture())
+	atl, err := srv.NewClient()
+	if err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+	return srv, atl
+}
+
+func TestClient_WorkLogs(t *testing.T) {
+	srv, atl := testServer(t)
+	defer srv.Close()
+
+	worklogs, err := atl.WorkLogs(context.Background(), "2020-10-31", "2020-10-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(worklogs) != 4 || worklogs[3].TempoWorklogID != 104 {
+		t.Fatalf("ERROR: expected the 4 October worklogs, got %+v", worklogs)
+	}
+	// Two worklogs per page.
+	if len(srv.Requests) != 2 {
+		t.Fatalf("ERROR: expected 2 pages to be requested, got %v", srv.Requests)
+	}
+	t.Logf("SUCCESS: fetched %d worklogs over %d pages", len(worklogs), len(srv.Requests))
+}
+
+func TestClient_JiraIssues(t *testing.T) {
+	srv, atl := testServer(t)
+	defer srv.Close()
+
+	issues, err := atl.JiraIssues(context.Background(), []string{"NTC-1", "OPS-7", "NTC-1", "NTC-404", "NTC-2", "OLD-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 4 || issues["OPS-7"].Fields.Project.Key != "OPS" {
+		t.Fatalf("ERROR: expected 4 issues, got %v", issues)
+	}
+	// OLD-1 was moved to OPS-7, it is found under the key asked for.
+	if issues["OLD-1"].Key != "OPS-7" {
+		t.Fatalf("ERROR: expected OLD-1 to be OPS-7, got %+v", issues["OLD-1"])
+	}
+	// One search over two pages and a lookup of each key it left out, the
+	// issues after that come from the cache.
+	for _, key := range []string{"NTC-2", "OLD-1"} {
+		if _, err := atl.JiraIssue(context.Background(), key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := "GET /rest/api/2/search,GET /rest/api/2/search,GET /rest/api/2/issue/NTC-404,GET /rest/api/2/issue/OLD-1"
+	if strings.Join(srv.Requests, ",") != expected {
+		t.Fatalf("ERROR: expected %s, got %v", expected, srv.Requests)
+	}
+
+	_, err = atl.JiraIssue(context.Background(), "NTC-404")
+	var apiErr *atlassian.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("ERROR: expected a 404 for NTC-404, got %v", err)
+	}
+	t.Logf("SUCCESS: fetched %d issues in one search", len(issues))
+}
+
+func TestClient_Search(t *testing.T) {
+	srv, atl := testServer(t)
+	defer srv.Close()
+
+	issues, total, err := atl.Search(context.Background(),
+		"assignee = currentUser() AND resolution = Unresolved ORDER BY updated DESC", atlassian.SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(issues) != 2 || issues[0].Key != "NTC-1" || issues[1].Fields.Assignee.DisplayName != "Fake User" {
+		t.Fatalf("ERROR: expected NTC-1 and OPS-7, got %d of %d: %+v", len(issues), total, issues)
+	}
+
+	// The limit stops the search before the last page.
+	issues, total, err = atl.Search(context.Background(), "project != OPS", atlassian.SearchOptions{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(issues) != 1 || len(srv.Requests) != 2 {
+		t.Fatalf("ERROR: expected 1 of 2 issues in a single request, got %d of %d over %v", len(issues), total, srv.Requests)
+	}
+	t.Logf("SUCCESS: searched %d issues", total)
+}
+
+func TestClient_SearchEnhanced(t *testing.T) {
+	srv := fake.Start(t, fake.SharedFixture())
+	defer srv.Close()
+	srv.JiraSearch = atlassian.SearchEnhanced
+	atl, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every issue over two pages, counted as they are read.
+	issues, total, err := atl.Search(context.Background(), "project != ABC", atlassian.SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "GET /rest/api/2/search/jql,GET /rest/api/2/search/jql"
+	if total != 3 || len(issues) != 3 || strings.Join(srv.Requests, ",") != expected {
+		t.Fatalf("ERROR: expected 3 issues over %s, got %d of %d over %v", expected, len(issues), total, srv.Requests)
+	}
+	// The limit leaves issues out without knowing how many.
+	if issues, total, err = atl.Search(context.Background(), "project != ABC", atlassian.SearchOptions{Limit: 2}); err != nil ||
+		total != -1 || len(issues) != 2 {
+		t.Fatalf("ERROR: expected 2 issues and no total, got %d of %d, %v", len(issues), total, err)
+	}
+
+	// An unknown key fails the batch, which is looked up key by key.
+	srv.Requests = nil
+	found, err := atl.JiraIssues(context.Background(), []string{"OPS-7", "NTC-404"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "GET /rest/api/2/search/jql,GET /rest/api/2/issue/OPS-7,GET /rest/api/2/issue/NTC-404"
+	if len(found) != 1 || found["OPS-7"].Key != "OPS-7" || strings.Join(srv.Requests, ",") != expected {
+		t.Fatalf("ERROR: expected OPS-7 over %s, got %v over %v", expected, found, srv.Requests)
+	}
+	t.Logf("SUCCESS: searched %d issues with the enhanced search", total)
+}
+
+func TestClient_NewIssue(t *testing.T) {
+	srv, atl := testServer(t)
+	defer srv.Close()
+
+	var req atlassian.IssueRequest
+	req.Fields.Project.Key = "NTC"
+	req.Fields.Summary = "Created from a test"
+	req.Fields.IssueType.Name = "Task"
+	resp, err := atl.NewIssue(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Key != "NTC-3" || len(srv.Created) != 1 {
+		t.Fatalf("ERROR: expected NTC-3 to be created, got %+v", resp)
+	}
+
+	req.Fields.Summary = ""
+	_, err = atl.NewIssue(context.Background(), req)
+	var apiErr *atlassian.APIError
+	if !errors.As(err, &apiErr) || apiErr.Fields["summary"] == "" {
+		t.Fatalf("ERROR: expected the missing summary to be reported, got %v", err)
+	}
+
+	srv.Script(fake.Response{Method: http.MethodPost, Path: "/rest/api/2/issue/", Status: http.StatusServiceUnavailable})
+	req.Fields.Summary = "Created from a test"
+	if _, err = atl.NewIssue(context.Background(), req); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("ERROR: expected the scripted 503, got %v", err)
+	}
+	t.Logf("SUCCESS: created %s", resp.Key)
+}
+
+func TestClient_NewIssueADF(t *testing.T) {
+	srv := fake.Start(t, fake.SharedFixture())
+	defer srv.Close()
+	opts := srv.Options()
+	opts.JiraAPIVersion = "3"
+	atl, err := atlassian.New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Version 3 turns a plain string description away, the fake decodes the ADF back to text.
+	var req atlassian.IssueRequest
+	req.Fields.Project.Key = "NTC"
+	req.Fields.Summary = "Created on API v3"
+	req.Fields.IssueType.Name = "Task"
+	req.Fields.Description = "Back up the configs\nnightly.\n\n# step one"
+	req.Fields.Custom = map[string]interface{}{"customfield_10010": "ACME"}
+	if _, err := atl.NewIssue(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	created := srv.Created[0].Fields
+	if created.Description != req.Fields.Description || created.Custom["customfield_10010"] != "ACME" {
+		t.Fatalf("ERROR: unexpected fields %+v", created)
+	}
+	if req.Fields.Custom["description"] != nil {
+		t.Fatalf("ERROR: expected the request of the caller to be left alone, got %v", req.Fields.Custom)
+	}
+	t.Logf("SUCCESS: created an issue with the description %q", created.Description)
+}
+
+func TestClient_NewWorklog(t *testing.T) {
+	srv, atl := testServer(t)
+	defer srv.Close()
+
+	accountID, err := atl.AccountID(context.Background())
+	if err != nil || accountID != "abc123" {
+		t.Fatalf("ERROR: expected the fixture user, got %q, %v", accountID, err)
+	}
+
+	worklog, err := atl.NewWorklog(context.Background(), atlassian.WorklogRequest{
+		IssueKey:         "NTC-1",
+		TimeSpentSeconds: 5400,
+		StartDate:        "2020-10-06",
+		Description:      "Logged from a test",
+		AuthorAccountID:  accountID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if worklog.TempoWorklogID != 106 || worklog.Issue.Key != "NTC-1" || worklog.BillableSeconds != 5400 {
+		t.Fatalf("ERROR: unexpected worklog %+v", worklog)
+	}
+
+	_, err = atl.NewWorklog(context.Background(), atlassian.WorklogRequest{
+		IssueKey:         "NTC-404",
+		TimeSpentSeconds: 60,
+		StartDate:        "2020-10-06",
+		AuthorAccountID:  accountID,
+	})
+	var apiErr *atlassian.APIError
+	if !errors.As(err, &apiErr) || len(apiErr.Messages) != 1 || apiErr.Messages[0] != "Issue not found: NTC-404" {
+		t.Fatalf("ERROR: expected the Tempo error, got %v", err)
+	}
+	t.Logf("SUCCESS: logged worklog %d", worklog.TempoWorklogID)
+}
+
+func TestClient_UpdateWorklog(t *testing.T) {
+	srv, atl := testServer(t)
+	defer srv.Close()
+
+	worklog, err := atl.Worklog(context.Background(), 102)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := worklog.Request()
+	req.TimeSpentSeconds = 2700
+	req.Description = "Edited from a test"
+	updated, err := atl.UpdateWorklog(context.Background(), 102, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.TempoWorklogID != 102 || updated.TimeSpentSeconds != 2700 || updated.StartDate != worklog.StartDate ||
+		updated.Author.AccountID != worklog.Author.AccountID || len(updated.Attributes.Values) != len(worklog.Attributes.Values) {
+		t.Fatalf("ERROR: unexpected update %+v of %+v", updated, worklog)
+	}
+
+	if err := atl.DeleteWorklog(context.Background(), 102); err != nil {
+		t.Fatal(err)
+	}
+	_, err = atl.Worklog(context.Background(), 102)
+	var apiErr *atlassian.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("ERROR: expected the deleted worklog to be gone, got %v", err)
+	}
+	t.Logf("SUCCESS: updated and deleted worklog %d", worklog.TempoWorklogID)
+}
+
+func TestClient_CreateMeta(t *testing.T) {
+	srv, atl := testServer(t)
+	defer srv.Close()
+
+	meta, err := atl.CreateMeta(context.Background(), "ntc", "change")
+	if err != nil {
+		t.Fatal(err)
+	}
+	issueType, err := meta.IssueType("NTC", "Change")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := meta.IssueType("NTC", "Sub-task"); err == nil {
+		t.Fatal("ERROR: expected only the Change issue type to be fetched")
+	}
+
+	var req atlassian.IssueRequest
+	req.Fields.Project.Key = "NTC"
+	req.Fields.Summary = "Created from a test"
+	req.Fields.IssueType.Name = "Change"
+	req.Fields.Custom = make(map[string]interface{})
+	for name, raw := range map[string]string{"customer": "acme", "Sites": "dfw3, IAD2", "story points": "3"} {
+		id, field, err := issueType.Field(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if req.Fields.Custom[id], err = field.Value(raw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, customer, err := issueType.Field("Customer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := customer.Value("Initech"); err == nil {
+		t.Fatal("ERROR: expected a value that is not allowed to be rejected")
+	}
+
+	// Account is required as well.
+	_, err = atl.NewIssue(context.Background(), req)
+	var apiErr *atlassian.APIError
+	if !errors.As(err, &apiErr) || apiErr.Fields["customfield_10011"] == "" {
+		t.Fatalf("ERROR: expected the missing Account to be reported, got %v", err)
+	}
+	req.Fields.Custom["customfield_10011"] = "ACME-001"
+	if _, err := atl.NewIssue(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	custom := srv.Created[0].Fields.Custom
+	if custom["customfield_10010"].(map[string]interface{})["value"] != "ACME" || len(custom["customfield_10014"].([]interface{})) != 2 ||
+		custom["customfield_10012"] != 3.0 {
+		t.Fatalf("ERROR: unexpected custom fields %v", custom)
+	}
+	t.Logf("SUCCESS: created an issue with %d custom fields", len(custom))
+}
+
+func TestClient_CreateMetaPaged(t *testing.T) {
+	srv, legacy := testServer(t)
+	defer srv.Close()
+	srv.JiraSearch = atlassian.SearchEnhanced
+	atl, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := legacy.CreateMeta(context.Background(), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Requests = nil
+	got, err := atl.CreateMeta(context.Background(), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Projects) != len(want.Projects) {
+		t.Fatalf("ERROR: expected %d projects, got %+v", len(want.Projects), got)
+	}
+	for i, p := range want.Projects {
+		if got.Projects[i].Key != p.Key || len(got.Projects[i].IssueTypes) != len(p.IssueTypes) {
+			t.Fatalf("ERROR: expected the issue types of %s, got %+v", p.Key, got.Projects[i])
+		}
+		for j, issueType := range p.IssueTypes {
+			fields := got.Projects[i].IssueTypes[j].Fields
+			if len(fields) != len(issueType.Fields) {
+				t.Fatalf("ERROR: expected %d fields on %s, got %d", len(issueType.Fields), issueType.Name, len(fields))
+			}
+			for id, f := range issueType.Fields {
+				if fields[id].Name != f.Name || fields[id].Required != f.Required || len(fields[id].AllowedValues) != len(f.AllowedValues) {
+					t.Fatalf("ERROR: expected %s to be %+v, got %+v", id, f, fields[id])
+				}
+			}
+		}
+	}
+	for _, req := range srv.Requests {
+		if strings.HasSuffix(req, "/issue/createmeta") {
+			t.Fatalf("ERROR: expected only the per-project create metadata to be used, got %v", srv.Requests)
+		}
+	}
+
+	meta, err := atl.CreateMeta(context.Background(), "ntc", "change")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := meta.IssueType("NTC", "Sub-task"); err == nil {
+		t.Fatal("ERROR: expected only the Change issue type to be fetched")
+	}
+	if meta, err = atl.CreateMeta(context.Background(), "nope", ""); err != nil || len(meta.Projects) != 0 {
+		t.Fatalf("ERROR: expected an unknown project to be left out, got %+v (%v)", meta, err)
+	}
+	t.Logf("SUCCESS: paged through the create metadata in %d requests", len(srv.Requests))
+}
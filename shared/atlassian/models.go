@@ -1,6 +1,9 @@
 package atlassian
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type (
 	// WorkLogResponse : structure to represent the response payload for a /workload requests.
@@ -38,11 +41,38 @@ type (
 			DisplayName string `json:"displayName"`
 		} `json:"author"`
 		Attributes struct {
-			Self   string        `json:"self"`
-			Values []interface{} `json:"values"`
+			Self   string             `json:"self"`
+			Values []WorklogAttribute `json:"values"`
 		} `json:"attributes"`
 	}
 
+	// WorklogAttribute : custom Tempo work attribute set on a worklog, e.g. the account.
+	WorklogAttribute struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+
+	// WorklogRequest : structure to create or update a worklog in Tempo. BillableSeconds
+	// is left to Tempo, which bills the time spent unless the account says otherwise.
+	WorklogRequest struct {
+		IssueKey         string             `json:"issueKey"`
+		TimeSpentSeconds int                `json:"timeSpentSeconds"`
+		BillableSeconds  *int               `json:"billableSeconds,omitempty"`
+		StartDate        string             `json:"startDate"`
+		StartTime        string             `json:"startTime,omitempty"`
+		Description      string             `json:"description"`
+		AuthorAccountID  string             `json:"authorAccountId"`
+		Attributes       []WorklogAttribute `json:"attributes,omitempty"`
+	}
+
+	// User : structure that represents a JIRA user, as returned by /myself.
+	User struct {
+		Self         string `json:"self"`
+		AccountID    string `json:"accountId"`
+		EmailAddress string `json:"emailAddress"`
+		DisplayName  string `json:"displayName"`
+	}
+
 	// JIRAIssue : structure that reprosents the response payload of a JIRA issue.
 	JIRAIssue struct {
 		Expand string `json:"expand"`
@@ -56,8 +86,10 @@ type (
 				Name    string `json:"name"`
 				ID      string `json:"id"`
 			} `json:"priority"`
-			Assignee interface{} `json:"assignee"`
-			Status   struct {
+			Assignee  *User     `json:"assignee"`
+			IssueType IssueName `json:"issuetype"`
+			Labels    []string  `json:"labels"`
+			Status    struct {
 				Self           string `json:"self"`
 				Description    string `json:"description"`
 				IconURL        string `json:"iconUrl"`
@@ -74,6 +106,7 @@ type (
 			Project struct {
 				Self string `json:"self"`
 				ID   string `json:"id"`
+				Key  string `json:"key"`
 				Name string `json:"name"`
 			} `json:"project"`
 			Created string `json:"created"`
@@ -82,6 +115,89 @@ type (
 		} `json:"fields"`
 	}
 
+	// IssueDetail : structure for an issue with everything shown by halp jira view. The
+	// description and comment bodies are wiki markup with API version 2 and Atlassian
+	// Document Format with version 3, see Text. The custom fields that have a value are
+	// kept in Custom by id, Names has the name of every field.
+	IssueDetail struct {
+		ID     string                     `json:"id"`
+		Key    string                     `json:"key"`
+		Self   string                     `json:"self"`
+		Names  map[string]string          `json:"names,omitempty"`
+		Custom map[string]json.RawMessage `json:"-"`
+		Fields struct {
+			Summary   string    `json:"summary"`
+			IssueType IssueName `json:"issuetype"`
+			Status    IssueName `json:"status"`
+			Priority  IssueName `json:"priority"`
+			Project   struct {
+				Key  string `json:"key"`
+				Name string `json:"name"`
+			} `json:"project"`
+			Assignee    *User           `json:"assignee"`
+			Reporter    *User           `json:"reporter"`
+			Labels      []string        `json:"labels"`
+			Created     string          `json:"created"`
+			Updated     string          `json:"updated"`
+			Description json.RawMessage `json:"description"`
+			Parent      *LinkedIssue    `json:"parent"`
+			Subtasks    []LinkedIssue   `json:"subtasks"`
+			IssueLinks  []IssueLink     `json:"issuelinks"`
+			Comment     struct {
+				Comments []Comment `json:"comments"`
+				Total    int       `json:"total"`
+			} `json:"comment"`
+		} `json:"fields"`
+	}
+
+	// LinkedIssue : structure for an issue referenced by another, such as a sub-task or link.
+	LinkedIssue struct {
+		ID     string `json:"id"`
+		Key    string `json:"key"`
+		Fields struct {
+			Summary   string    `json:"summary"`
+			Status    IssueName `json:"status"`
+			IssueType IssueName `json:"issuetype"`
+		} `json:"fields"`
+	}
+
+	// IssueLink : structure for a link between two issues. Only one of InwardIssue and
+	// OutwardIssue is set, the other end being the issue holding the link.
+	IssueLink struct {
+		ID   string `json:"id"`
+		Type struct {
+			Name    string `json:"name"`
+			Inward  string `json:"inward"`
+			Outward string `json:"outward"`
+		} `json:"type"`
+		InwardIssue  *LinkedIssue `json:"inwardIssue,omitempty"`
+		OutwardIssue *LinkedIssue `json:"outwardIssue,omitempty"`
+	}
+
+	// Comment : structure for a comment on an issue.
+	Comment struct {
+		ID      string          `json:"id"`
+		Author  User            `json:"author"`
+		Body    json.RawMessage `json:"body"`
+		Created string          `json:"created"`
+	}
+
+	// SearchResponse : structure that represents a page of results from the JIRA search API.
+	SearchResponse struct {
+		StartAt    int         `json:"startAt"`
+		MaxResults int         `json:"maxResults"`
+		Total      int         `json:"total"`
+		Issues     []JIRAIssue `json:"issues"`
+	}
+
+	// SearchJQLResponse : structure that represents a page of results from the enhanced
+	// search API of JIRA Cloud, which has no total.
+	SearchJQLResponse struct {
+		Issues        []JIRAIssue `json:"issues"`
+		NextPageToken string      `json:"nextPageToken,omitempty"`
+		IsLast        bool        `json:"isLast"`
+	}
+
 	// IssueRequest : structure to create a new issue in Atlassian JIRA.
 	IssueRequest struct {
 		Fields IssueField `json:"fields"`
@@ -97,6 +213,95 @@ type (
 		IssueType   struct {
 			Name string `json:"name"`
 		} `json:"issuetype"`
+		Priority   *IssueName  `json:"priority,omitempty"`
+		Labels     []string    `json:"labels,omitempty"`
+		Assignee   *IssueUser  `json:"assignee,omitempty"`
+		Parent     *IssueKey   `json:"parent,omitempty"`
+		Components []IssueName `json:"components,omitempty"`
+		// Custom holds the fields without a struct field of their own, such as
+		// customfield_10010, already converted to the JSON shape JIRA expects. They
+		// are encoded over the struct fields, e.g. the ADF description of API v3.
+		Custom map[string]interface{} `json:"-"`
+	}
+
+	// CreateMeta : The projects and issue types issues can be created with, along with
+	// the fields each issue type takes.
+	CreateMeta struct {
+		Projects []ProjectMeta `json:"projects"`
+	}
+
+	// ProjectMeta : A project issues can be created in and its issue types.
+	ProjectMeta struct {
+		Key        string          `json:"key"`
+		Name       string          `json:"name"`
+		IssueTypes []IssueTypeMeta `json:"issuetypes"`
+	}
+
+	// CreateMetaPage : structure that represents a page of the per-project create
+	// metadata of JIRA Cloud, listing either the issue types of a project or the
+	// fields of an issue type.
+	CreateMetaPage struct {
+		StartAt    int             `json:"startAt"`
+		MaxResults int             `json:"maxResults"`
+		Total      int             `json:"total"`
+		IssueTypes []IssueTypeMeta `json:"issueTypes,omitempty"`
+		Fields     []FieldMeta     `json:"fields,omitempty"`
+	}
+
+	// ProjectPage : structure that represents a page of the projects of JIRA Cloud.
+	ProjectPage struct {
+		StartAt    int           `json:"startAt"`
+		MaxResults int           `json:"maxResults"`
+		Total      int           `json:"total"`
+		IsLast     bool          `json:"isLast"`
+		Values     []ProjectMeta `json:"values"`
+	}
+
+	// IssueTypeMeta : An issue type of a project and the fields it takes, keyed by field ID.
+	IssueTypeMeta struct {
+		ID     string               `json:"id"`
+		Name   string               `json:"name"`
+		Fields map[string]FieldMeta `json:"fields"`
+	}
+
+	// FieldMeta : Describes a field of an issue type, its type and the values it allows.
+	FieldMeta struct {
+		// FieldID is only set in the pages of the per-project create metadata,
+		// elsewhere the fields are keyed by it.
+		FieldID  string `json:"fieldId,omitempty"`
+		Key      string `json:"key"`
+		Name     string `json:"name"`
+		Required bool   `json:"required"`
+		Schema   struct {
+			Type   string `json:"type"`
+			Items  string `json:"items,omitempty"`
+			System string `json:"system,omitempty"`
+			Custom string `json:"custom,omitempty"`
+		} `json:"schema"`
+		AllowedValues []AllowedValue `json:"allowedValues,omitempty"`
+	}
+
+	// AllowedValue : A value a field allows. Options carry a value, while priorities,
+	// components and versions carry a name.
+	AllowedValue struct {
+		ID    string `json:"id,omitempty"`
+		Name  string `json:"name,omitempty"`
+		Value string `json:"value,omitempty"`
+	}
+
+	// IssueName : Reference to a field value by name, such as a priority or component.
+	IssueName struct {
+		Name string `json:"name"`
+	}
+
+	// IssueKey : Reference to another issue by key, such as the parent of a sub-task.
+	IssueKey struct {
+		Key string `json:"key"`
+	}
+
+	// IssueUser : Reference to a JIRA user by account ID.
+	IssueUser struct {
+		AccountID string `json:"accountId"`
 	}
 
 	// IssueResponse : When a new issue is created, this will be the response payload.
@@ -0,0 +1,213 @@
+package atlassian
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// RetryPolicy : How the client retries requests that were rate limited or hit a
+	// server error. The wait between attempts doubles from MinWait up to MaxWait, with
+	// jitter, unless the response says how long to wait with Retry-After, which is
+	// capped at MaxWait too. No retry is made that would wait past the deadline.
+	RetryPolicy struct {
+		Retries int
+		MinWait time.Duration
+		MaxWait time.Duration
+	}
+
+	// request : A single API call made through client.do.
+	request struct {
+		// name prefixes the errors returned for the call, e.g. jira.Issue.
+		name   string
+		method string
+		url    string
+		// body is marshalled to JSON when set.
+		body interface{}
+		// auth sets the credentials of the API the call is made to.
+		auth func(*http.Request)
+	}
+)
+
+// DefaultRetryPolicy : The policy used unless another is given in Options.
+var DefaultRetryPolicy = RetryPolicy{Retries: 3, MinWait: 500 * time.Millisecond, MaxWait: 30 * time.Second}
+
+// jiraAuth : Sets the JIRA credentials on a request, a personal access token for
+// bearer auth or the username and API token for basic auth.
+func (c *Client) jiraAuth(req *http.Request) {
+	if c.auth == AuthBearer {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.jiraToken))
+		return
+	}
+	req.SetBasicAuth(c.jiraUser, c.jiraToken)
+}
+
+// tempoAuth : Sets the Tempo bearer token on a request.
+func (c *Client) tempoAuth(req *http.Request) {
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.tempoToken))
+}
+
+// do : Executes the request, retrying it according to the retry policy, and decodes
+// the JSON response into out unless it is nil. Every API call goes through here.
+func (c *Client) do(ctx context.Context, r request, out interface{}) error {
+	var body []byte
+	if r.body != nil {
+		var err error
+		if body, err = json.Marshal(r.body); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		res, err := c.send(ctx, r, body)
+		if err == nil && res.StatusCode <= http.StatusNoContent {
+			return decode(res, out)
+		}
+
+		var (
+			reason   string
+			retry    bool
+			wait     = c.backoff(attempt)
+			finalErr = err
+		)
+		if err != nil {
+			// A request that failed in flight may still have been applied, so
+			// only requests that are safe to repeat are retried.
+			reason, retry = err.Error(), idempotent(r.method)
+		} else {
+			reason = res.Status
+			retry = retryable(r.method, res.StatusCode)
+			if after, ok := retryAfter(res.Header.Get("Retry-After"), time.Now()); ok {
+				wait = after
+				if wait > c.retry.MaxWait {
+					wait = c.retry.MaxWait
+				}
+			}
+			finalErr = newAPIError(r, res)
+			drain(res)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !retry || attempt >= c.retry.Retries {
+			if retry {
+				logrus.Debugf("%s: %s %s failed with %s, giving up after %d attempts",
+					r.name, r.method, r.url, reason, attempt+1)
+			}
+			return finalErr
+		}
+
+		// Waiting past the deadline would only trade the API error for a timeout.
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			logrus.Debugf("%s: %s %s failed with %s, not retrying in %s past the deadline",
+				r.name, r.method, r.url, reason, wait.Round(time.Millisecond))
+			return finalErr
+		}
+		logrus.Debugf("%s: %s %s failed with %s, retrying in %s (retry %d of %d)",
+			r.name, r.method, r.url, reason, wait.Round(time.Millisecond), attempt+1, c.retry.Retries)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// send : Makes a single attempt of the request.
+func (c *Client) send(ctx context.Context, r request, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, r.method, r.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if r.auth != nil {
+		r.auth(req)
+	}
+	return c.client.Do(req)
+}
+
+// backoff : The jittered exponential wait before the given retry.
+func (c *Client) backoff(attempt int) time.Duration {
+	wait := c.retry.MinWait
+	for i := 0; i < attempt && wait < c.retry.MaxWait; i++ {
+		wait *= 2
+	}
+	if wait > c.retry.MaxWait {
+		wait = c.retry.MaxWait
+	}
+	if wait <= 0 {
+		return 0
+	}
+	// Wait somewhere between half and all of it, so that concurrent
+	// requests that failed together don't all retry together.
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// decode : Reads the JSON response into out and closes the body.
+func decode(res *http.Response, out interface{}) (err error) {
+	// Note: We use a func to error check defer as opposed to using
+	// defer res.Body.Close(), which will never return an error.
+	defer func() {
+		if defErr := res.Body.Close(); defErr != nil && err == nil {
+			err = defErr
+		}
+	}()
+	if out == nil || res.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// drain : Closes the body of a failed response once its error details have been read.
+func drain(res *http.Response) {
+	_ = decode(res, nil)
+}
+
+// idempotent : Whether repeating the request has the same effect as making it once.
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryable : Whether a response status is worth retrying. A 429 means the request
+// was turned away before it was processed, so it is retried for every method. Server
+// errors are only retried for idempotent requests, a POST may have been applied.
+func retryable(method string, status int) bool {
+	switch status {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotent(method)
+	}
+	return false
+}
+
+// retryAfter : Parses a Retry-After header, given either in seconds or as a date.
+func retryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		if wait := at.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,148 @@
+package atlassian
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testClient returns a client pointed at a TLS test server answering with the
+// statuses passed in, one per request, followed by 200 responses.
+func testClient(t *testing.T, statuses ...int) (*Client, *int32, func()) {
+	var calls int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1))
+		w.Header().Set("Content-Type", "application/json")
+		if n <= len(statuses) {
+			if statuses[n-1] == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "0")
+			}
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": "10001", "key": "NTC-1", "fields": {"summary": "test"}}`))
+	}))
+	c, err := New(Options{
+		JiraURL:    srv.URL,
+		JiraUser:   "user",
+		JiraToken:  "token",
+		Retry:      &RetryPolicy{Retries: 2, MinWait: time.Millisecond, MaxWait: 5 * time.Millisecond},
+		HTTPClient: srv.Client(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, &calls, srv.Close
+}
+
+func TestClient_do(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		post     bool
+		calls    int32
+		fail     bool
+	}{
+		{"ok", nil, false, 1, false},
+		{"rate limited", []int{429}, false, 2, false},
+		{"server errors", []int{503, 502}, false, 3, false},
+		{"retries exhausted", []int{500, 500, 500}, false, 3, true},
+		{"not found", []int{404}, false, 1, true},
+		{"post rate limited", []int{429}, true, 2, false},
+		{"post server error", []int{503}, true, 1, true},
+	}
+	for _, test := range tests {
+		c, calls, done := testClient(t, test.statuses...)
+		var err error
+		if test.post {
+			_, err = c.NewIssue(context.Background(), IssueRequest{})
+		} else {
+			_, err = c.JiraIssue(context.Background(), "NTC-1")
+		}
+		done()
+		if (err != nil) != test.fail {
+			t.Errorf("ERROR: %s: unexpected error %v", test.name, err)
+		}
+		if *calls != test.calls {
+			t.Errorf("ERROR: %s: expected %d requests, got %d", test.name, test.calls, *calls)
+		}
+	}
+	t.Logf("SUCCESS: retried requests according to their method and status")
+}
+
+func TestClient_doRetryAfter(t *testing.T) {
+	var calls int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": "10001", "key": "NTC-1", "fields": {"summary": "test"}}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		maxWait time.Duration
+		timeout time.Duration
+		calls   int32
+		fail    bool
+	}{
+		// The two minutes asked for are capped at MaxWait.
+		{"capped", 5 * time.Millisecond, time.Minute, 2, false},
+		// Waiting a minute would go past the deadline, the 429 is returned instead.
+		{"past the deadline", time.Minute, time.Second, 1, true},
+	}
+	for _, test := range tests {
+		atomic.StoreInt32(&calls, 0)
+		c, err := New(Options{
+			JiraURL:    srv.URL,
+			Retry:      &RetryPolicy{Retries: 2, MinWait: time.Millisecond, MaxWait: test.maxWait},
+			HTTPClient: srv.Client(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), test.timeout)
+		start := time.Now()
+		err = c.do(ctx, request{name: "jira.Issue", method: http.MethodGet, url: srv.URL}, nil)
+		cancel()
+		var apiErr *APIError
+		if test.fail && (!errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests) {
+			t.Errorf("ERROR: %s: expected the 429 APIError, got %v", test.name, err)
+		}
+		if !test.fail && err != nil {
+			t.Errorf("ERROR: %s: unexpected error %v", test.name, err)
+		}
+		if calls != test.calls || time.Since(start) > 500*time.Millisecond {
+			t.Errorf("ERROR: %s: expected %d requests without waiting, got %d in %s", test.name, test.calls, calls, time.Since(start))
+		}
+	}
+	t.Logf("SUCCESS: capped Retry-After and gave up before the deadline")
+}
+
+func Test_retryAfter(t *testing.T) {
+	now := time.Date(2020, 10, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header string
+		wait   time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"Thu, 15 Oct 2020 12:00:30 GMT", 30 * time.Second, true},
+		{"Thu, 15 Oct 2020 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, test := range tests {
+		if wait, ok := retryAfter(test.header, now); wait != test.wait || ok != test.ok {
+			t.Errorf("ERROR: Retry-After %q: got %s %t, expected %s %t", test.header, wait, ok, test.wait, test.ok)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package atlassian
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/josh5276/halp/core/keyring"
+	"github.com/josh5276/halp/shared/cache"
+)
+
+// section is the settings.ini section holding the client options.
+const section = "atlassian"
+
+// FromSettings : Creates a client for the JIRA instance and Tempo account in the
+// settings file, with the tokens kept in the keyring and the persistent issue cache.
+func FromSettings(cfg *keyring.Settings) (*Client, error) {
+	opts, err := OptionsFromSettings(*cfg)
+	if err != nil {
+		return nil, err
+	}
+	tempoToken, err := cfg.TempoToken()
+	if err != nil {
+		return nil, fmt.Errorf("cfg.Tempo:%w", err)
+	}
+	jiraToken, err := cfg.JIRAToken()
+	if err != nil {
+		return nil, fmt.Errorf("cfg.JIRA:%w", err)
+	}
+	opts.TempoToken, opts.JiraToken = tempoToken.Password, jiraToken.Password
+
+	if opts.Cache, err = cache.FromSettings(*cfg); err != nil {
+		return nil, err
+	}
+	return New(opts)
+}
+
+// BrowseURL : The address of an issue in the JIRA web interface of the settings file.
+// Neither JIRA nor the keyring is asked, so it works without credentials.
+func BrowseURL(cfg keyring.Settings, issueKey string) (string, error) {
+	opts, err := OptionsFromSettings(cfg)
+	if err != nil {
+		return "", err
+	}
+	jiraURL, err := baseURL(opts.JiraURL)
+	if err != nil {
+		return "", fmt.Errorf("atlassian.BrowseURL:jira url:%s", err)
+	}
+	return fmt.Sprintf("%s/browse/%s", jiraURL, url.PathEscape(issueKey)), nil
+}
+
+// CachedProjects : The keys of the projects with issues in the persistent issue cache,
+// sorted. Neither JIRA nor the keyring is asked, so it is quick enough for completion.
+func CachedProjects(cfg keyring.Settings) ([]string, error) {
+	opts, err := OptionsFromSettings(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Cache, err = cache.FromSettings(cfg); err != nil {
+		return nil, err
+	}
+	c, err := New(opts)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	projects := make([]string, 0)
+	for _, key := range c.CachedIssueKeys() {
+		i := strings.LastIndex(key, "-")
+		if i <= 0 || seen[key[:i]] {
+			continue
+		}
+		seen[key[:i]] = true
+		projects = append(projects, key[:i])
+	}
+	sort.Strings(projects)
+	return projects, nil
+}
+
+// OptionsFromSettings : Reads the client options, other than the tokens and the cache,
+// from the base section and the optional [atlassian] section of the settings file, e.g.
+//
+//	[atlassian]
+//	jira_url          = https://jira.example.com
+//	jira_api_version  = 2
+//	jira_auth         = bearer
+//	jira_search       = legacy
+//	tempo_url         = https://api.eu.tempo.io
+//	tempo_api_version = core/3
+//	account_id        = 5b10a2844c20165700ede21g
+//	retries           = 3
+//	retry_wait        = 500ms
+//	retry_max_wait    = 30s
+//
+// The JIRA URL defaults to the jira_instance of the base section and the account ID
+// to the one of the authenticated user.
+func OptionsFromSettings(cfg keyring.Settings) (Options, error) {
+	opts := Options{
+		JiraURL:         cfg.Value(section, "jira_url"),
+		JiraAPIVersion:  cfg.Value(section, "jira_api_version"),
+		JiraAuth:        AuthScheme(cfg.Value(section, "jira_auth")),
+		JiraSearch:      SearchAPI(cfg.Value(section, "jira_search")),
+		JiraUser:        cfg.JIRAUser,
+		TempoURL:        cfg.Value(section, "tempo_url"),
+		TempoAPIVersion: cfg.Value(section, "tempo_api_version"),
+		AccountID:       cfg.Value(section, "account_id"),
+	}
+	if opts.JiraURL == "" {
+		opts.JiraURL = cfg.JIRAInstance
+	}
+	retry, err := RetryPolicyFromSettings(cfg)
+	if err != nil {
+		return opts, err
+	}
+	opts.Retry = &retry
+	return opts, nil
+}
+
+// RetryPolicyFromSettings : Reads the retry policy from the optional [atlassian]
+// section of the settings file. Keys that are not set keep their DefaultRetryPolicy
+// value, retries = 0 disables retrying.
+func RetryPolicyFromSettings(cfg keyring.Settings) (RetryPolicy, error) {
+	p := DefaultRetryPolicy
+	if v := cfg.Value(section, "retries"); v != "" {
+		retries, err := strconv.Atoi(v)
+		if err != nil || retries < 0 {
+			return p, fmt.Errorf("atlassian.retries: expected a number, got %q", v)
+		}
+		p.Retries = retries
+	}
+	for key, wait := range map[string]*time.Duration{"retry_wait": &p.MinWait, "retry_max_wait": &p.MaxWait} {
+		v := cfg.Value(section, key)
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return p, fmt.Errorf("atlassian.%s: expected a duration such as 500ms or 30s, got %q", key, v)
+		}
+		*wait = d
+	}
+	return p, nil
+}
@@ -0,0 +1,352 @@
+// Package cache is a small on-disk cache shared by the halp plugins, used to
+// keep API responses such as JIRA issues between runs.
+//
+// Entries live in buckets, one directory per bucket, and each entry is a JSON
+// file holding the value along with when it was stored and when the value
+// itself was last modified upstream. An entry is fresh for the configured TTL,
+// or for a tenth of the time it had gone unmodified when it was stored if that
+// is longer, up to the configured maximum. Values that have not changed in a
+// long time are unlikely to change soon, so they are trusted for longer. A
+// change upstream is not noticed until the entry expires.
+//
+// Every bucket directory holds a marker file, so that the cache only ever
+// lists or removes entries in directories it created itself, even when it is
+// pointed at a directory shared with other tools.
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/josh5276/halp/core/keyring"
+)
+
+const (
+	// section is the settings.ini section holding the cache options.
+	section = "cache"
+	// DefaultTTL is how long an entry is trusted when ttl is not configured.
+	DefaultTTL = time.Hour
+	// DefaultMaxTTL caps how long an entry that has not changed upstream in
+	// a long time is trusted when max_ttl is not configured.
+	DefaultMaxTTL = 24 * time.Hour
+	// marker is the file marking a directory as a bucket of the cache.
+	marker = ".halp-cache"
+)
+
+type (
+	// Cache is a directory of cached entries. The zero TTL disables it.
+	Cache struct {
+		Dir    string
+		TTL    time.Duration
+		MaxTTL time.Duration
+		now    func() time.Time
+	}
+
+	// entry is the file format of a single cached value.
+	entry struct {
+		Stored   time.Time       `json:"stored"`
+		Modified time.Time       `json:"modified"`
+		Value    json.RawMessage `json:"value"`
+	}
+
+	// Stats describes the entries held in a single bucket.
+	Stats struct {
+		Bucket  string
+		Entries int
+		Expired int
+		Bytes   int64
+		Oldest  time.Time
+	}
+)
+
+// New returns a cache rooted at dir. A maxTTL shorter than ttl is raised to it.
+func New(dir string, ttl, maxTTL time.Duration) *Cache {
+	if maxTTL < ttl {
+		maxTTL = ttl
+	}
+	return &Cache{Dir: dir, TTL: ttl, MaxTTL: maxTTL, now: time.Now}
+}
+
+// FromSettings builds the cache from the optional [cache] section of the
+// settings file, e.g.
+//
+//	[cache]
+//	dir     = /home/me/.cache/halp
+//	ttl     = 1h
+//	max_ttl = 24h
+//
+// The directory defaults to halp under the user cache directory
+// ($XDG_CACHE_HOME or ~/.cache on Linux), falling back to ~/.config/gokeys/cache.
+// Setting ttl to 0 disables the cache.
+func FromSettings(cfg keyring.Settings) (*Cache, error) {
+	dir := cfg.Value(section, "dir")
+	if dir == "" {
+		dir = defaultDir()
+	}
+	ttl, err := duration(cfg, "ttl", DefaultTTL)
+	if err != nil {
+		return nil, err
+	}
+	maxTTL, err := duration(cfg, "max_ttl", DefaultMaxTTL)
+	if err != nil {
+		return nil, err
+	}
+	return New(dir, ttl, maxTTL), nil
+}
+
+func duration(cfg keyring.Settings, key string, def time.Duration) (time.Duration, error) {
+	value := cfg.Value(section, key)
+	if value == "" {
+		return def, nil
+	}
+	if value == "0" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("cache.%s: expected a duration such as 1h or 30m, got %q", key, value)
+	}
+	return d, nil
+}
+
+func defaultDir() string {
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "halp")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "gokeys", "cache")
+}
+
+// Enabled reports whether the cache stores anything at all.
+func (c *Cache) Enabled() bool {
+	return c != nil && c.TTL > 0
+}
+
+// Get loads the entry stored under key into v, reporting whether a fresh
+// entry was found. Missing, expired and unreadable entries are all misses.
+func (c *Cache) Get(bucket, key string, v interface{}) bool {
+	if !c.Enabled() {
+		return false
+	}
+	e, err := readEntry(c.path(bucket, key))
+	if err != nil || !c.fresh(e) {
+		return false
+	}
+	return json.Unmarshal(e.Value, v) == nil
+}
+
+// Put stores v under key. modified is when the value last changed upstream,
+// or the zero time when unknown.
+func (c *Cache) Put(bucket, key string, modified time.Time, v interface{}) error {
+	if !c.Enabled() {
+		return nil
+	}
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry{Stored: c.now(), Modified: modified, Value: value})
+	if err != nil {
+		return err
+	}
+	path := c.path(bucket, key)
+	if err := mkBucket(filepath.Dir(path)); err != nil {
+		return err
+	}
+	// Write to a temporary file first so that a concurrent reader never
+	// sees a partially written entry.
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Keys returns the keys stored in a bucket, ordered by name. Expired entries
+// are included, they are still good enough to suggest, e.g. for completion.
+func (c *Cache) Keys(bucket string) []string {
+	keys := make([]string, 0)
+	if !c.Enabled() {
+		return keys
+	}
+	dir := filepath.Dir(c.path(bucket, ""))
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return keys
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		if key, err := url.PathUnescape(strings.TrimSuffix(f.Name(), ".json")); err == nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Stats describes every bucket in the cache, ordered by name.
+func (c *Cache) Stats() ([]Stats, error) {
+	stats := make(map[string]*Stats)
+	err := c.entries(func(bucket, path string, info os.FileInfo) {
+		s, ok := stats[bucket]
+		if !ok {
+			s = &Stats{Bucket: bucket}
+			stats[bucket] = s
+		}
+		s.Entries++
+		s.Bytes += info.Size()
+		e, err := readEntry(path)
+		if err != nil || !c.fresh(e) {
+			s.Expired++
+		}
+		if err == nil && (s.Oldest.IsZero() || e.Stored.Before(s.Oldest)) {
+			s.Oldest = e.Stored
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Stats, 0, len(stats))
+	for _, s := range stats {
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Bucket < list[j].Bucket })
+	return list, nil
+}
+
+// Clear removes every entry from the cache, returning how many were removed.
+// Only entry files, the markers of their buckets and the directories they leave
+// empty are removed, the cache directory may be shared with other tools.
+func (c *Cache) Clear() (int, error) {
+	removed := 0
+	dirs := make(map[string]bool)
+	err := c.entries(func(_, path string, _ os.FileInfo) {
+		if os.Remove(path) == nil {
+			removed++
+		}
+		for dir := filepath.Dir(path); dir != filepath.Clean(c.Dir); dir = filepath.Dir(dir) {
+			dirs[dir] = true
+		}
+	})
+	for dir := range dirs {
+		if unused(dir) {
+			os.Remove(filepath.Join(dir, marker))
+		}
+	}
+	// Remove the deepest directories first so their parents end up empty.
+	list := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		list = append(list, dir)
+	}
+	sort.Slice(list, func(i, j int) bool { return len(list[i]) > len(list[j]) })
+	for _, dir := range list {
+		os.Remove(dir)
+	}
+	return removed, err
+}
+
+// fresh reports whether the entry is still within its TTL.
+func (c *Cache) fresh(e entry) bool {
+	ttl := c.TTL
+	if !e.Modified.IsZero() {
+		if heuristic := e.Stored.Sub(e.Modified) / 10; heuristic > ttl {
+			ttl = heuristic
+		}
+	}
+	if ttl > c.MaxTTL {
+		ttl = c.MaxTTL
+	}
+	return c.now().Before(e.Stored.Add(ttl))
+}
+
+// entries calls fn for every entry file, with the bucket it belongs to. JSON
+// files outside of the marked bucket directories are not entries.
+func (c *Cache) entries(fn func(bucket, path string, info os.FileInfo)) error {
+	buckets := make(map[string]bool)
+	err := filepath.Walk(c.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".json") {
+			return nil
+		}
+		dir := filepath.Dir(path)
+		marked, ok := buckets[dir]
+		if !ok {
+			_, err := os.Stat(filepath.Join(dir, marker))
+			marked = err == nil
+			buckets[dir] = marked
+		}
+		if !marked {
+			return nil
+		}
+		rel, err := filepath.Rel(c.Dir, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		bucket, err := url.PathUnescape(filepath.ToSlash(rel))
+		if err != nil {
+			bucket = filepath.ToSlash(rel)
+		}
+		fn(bucket, path, info)
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// path returns the file of an entry. Bucket segments and keys are escaped so
+// that instance names and issue keys are always safe file names.
+func (c *Cache) path(bucket, key string) string {
+	parts := []string{c.Dir}
+	for _, segment := range strings.Split(bucket, "/") {
+		parts = append(parts, url.PathEscape(segment))
+	}
+	return filepath.Join(append(parts, url.PathEscape(key)+".json")...)
+}
+
+// mkBucket creates the directory of a bucket along with its marker.
+func mkBucket(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, marker)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	return ioutil.WriteFile(path, []byte("halp cache bucket, see halp cache clear\n"), 0600)
+}
+
+// unused reports whether nothing but its marker is left in a bucket directory.
+func unused(dir string) bool {
+	files, err := ioutil.ReadDir(dir)
+	return err == nil && len(files) == 1 && files[0].Name() == marker
+}
+
+func readEntry(path string) (entry, error) {
+	var e entry
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return e, err
+	}
+	return e, json.Unmarshal(data, &e)
+}
@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testValue struct {
+	Key     string
+	Summary string
+}
+
+func testCache(t *testing.T) (*Cache, *time.Time) {
+	dir, err := ioutil.TempDir("", "halp-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2020, 10, 15, 12, 0, 0, 0, time.UTC)
+	c := New(dir, time.Hour, 24*time.Hour)
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestCache_GetPut(t *testing.T) {
+	c, now := testCache(t)
+	defer os.RemoveAll(c.Dir)
+	bucket := "jira-issues/acme.atlassian.net"
+	start := *now
+
+	var v testValue
+	if c.Get(bucket, "NTC-1", &v) {
+		t.Fatal("ERROR: expected a miss on an empty cache")
+	}
+	// NTC-1 was just updated, NTC-2 has not changed in 100 days.
+	if err := c.Put(bucket, "NTC-1", start.Add(-time.Minute), testValue{Key: "NTC-1", Summary: "new"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Put(bucket, "NTC-2", start.AddDate(0, 0, -100), testValue{Key: "NTC-2", Summary: "old"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Put("other/bucket", "NTC-1", time.Time{}, testValue{Key: "NTC-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		after time.Duration
+		key   string
+		hit   bool
+	}{
+		{30 * time.Minute, "NTC-1", true},
+		{2 * time.Hour, "NTC-1", false},
+		{2 * time.Hour, "NTC-2", true},
+		{25 * time.Hour, "NTC-2", false},
+		{0, "NTC-3", false},
+	}
+	for _, test := range tests {
+		*now = start.Add(test.after)
+		v = testValue{}
+		if hit := c.Get(bucket, test.key, &v); hit != test.hit {
+			t.Errorf("ERROR: %s after %s: expected hit %t, got %t", test.key, test.after, test.hit, hit)
+		} else if hit && v.Key != test.key {
+			t.Errorf("ERROR: %s: loaded %+v", test.key, v)
+		}
+	}
+
+	*now = start.Add(2 * time.Hour)
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 || stats[0].Bucket != bucket || stats[0].Entries != 2 || stats[0].Expired != 1 {
+		t.Fatalf("ERROR: unexpected stats %+v", stats)
+	}
+	// NTC-1 has expired but is still listed.
+	if keys := c.Keys(bucket); strings.Join(keys, ",") != "NTC-1,NTC-2" {
+		t.Fatalf("ERROR: expected the keys NTC-1 and NTC-2, got %v", keys)
+	}
+
+	removed, err := c.Clear()
+	if err != nil || removed != 3 {
+		t.Fatalf("ERROR: expected 3 entries removed, got %d (%v)", removed, err)
+	}
+	if _, err := os.Stat(c.Dir); err != nil {
+		t.Fatalf("ERROR: expected the cache directory to be kept, %s", err)
+	}
+	if stats, _ = c.Stats(); len(stats) != 0 {
+		t.Fatalf("ERROR: expected an empty cache, got %+v", stats)
+	}
+	t.Logf("SUCCESS: cache entries expired according to their TTL")
+}
+
+func TestCache_ClearShared(t *testing.T) {
+	c, _ := testCache(t)
+	defer os.RemoveAll(c.Dir)
+
+	// Other tools keep their files in the same directory.
+	foreign := []string{filepath.Join(c.Dir, "settings.json"), filepath.Join(c.Dir, "other", "state.json")}
+	for _, path := range foreign {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte("{}"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Put("jira-issues/acme.atlassian.net", "NTC-1", time.Time{}, testValue{Key: "NTC-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if stats, err := c.Stats(); err != nil || len(stats) != 1 || stats[0].Entries != 1 {
+		t.Fatalf("ERROR: expected a single entry, got %+v (%v)", stats, err)
+	}
+	removed, err := c.Clear()
+	if err != nil || removed != 1 {
+		t.Fatalf("ERROR: expected 1 entry removed, got %d (%v)", removed, err)
+	}
+	for _, path := range foreign {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("ERROR: expected %s to be kept, %s", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(c.Dir, "jira-issues")); !os.IsNotExist(err) {
+		t.Fatalf("ERROR: expected the emptied bucket to be removed, %v", err)
+	}
+	t.Logf("SUCCESS: cleared the cache and kept %d foreign files", len(foreign))
+}
+
+func TestCache_Disabled(t *testing.T) {
+	c, _ := testCache(t)
+	defer os.RemoveAll(c.Dir)
+	c.TTL = 0
+	if err := c.Put("b", "k", time.Time{}, testValue{Key: "k"}); err != nil {
+		t.Fatal(err)
+	}
+	var v testValue
+	if c.Get("b", "k", &v) {
+		t.Fatal("ERROR: expected a disabled cache to never hit")
+	}
+	var nilCache *Cache
+	if nilCache.Get("b", "k", &v) || nilCache.Put("b", "k", time.Time{}, v) != nil {
+		t.Fatal("ERROR: expected a nil cache to be disabled")
+	}
+}
@@ -0,0 +1,117 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DateLayout is the calendar date layout used on the command line and by
+// the Tempo API.
+const DateLayout = "2006-01-02"
+
+type (
+	// DateRange is an inclusive range of calendar days. From is the start
+	// of the first day and To the start of the last day, in the location
+	// the range was resolved in.
+	DateRange struct {
+		From time.Time
+		To   time.Time
+	}
+
+	// DateRangeArgs holds the flags registered by ArgDateRange.
+	DateRangeArgs struct {
+		from      *string
+		to        *string
+		lastMonth *bool
+		thisWeek  *bool
+		lastWeek  *bool
+		days      *int
+	}
+)
+
+// String returns the range as it is shown in report headers.
+func (d DateRange) String() string {
+	return fmt.Sprintf("%s to %s", d.From.Format(DateLayout), d.To.Format(DateLayout))
+}
+
+// Days returns every day in the range, in order.
+func (d DateRange) Days() []time.Time {
+	days := make([]time.Time, 0)
+	for day := d.From; !day.After(d.To); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	return days
+}
+
+// Contains reports whether t falls on one of the days in the range.
+func (d DateRange) Contains(t time.Time) bool {
+	t = startOfDay(t.In(d.From.Location()))
+	return !t.Before(d.From) && !t.After(d.To)
+}
+
+// Range resolves the flags into a DateRange relative to now, interpreting
+// dates in loc. Without any flags it defaults to the first of the current
+// month through today.
+func (a *DateRangeArgs) Range(now time.Time, loc *time.Location) (DateRange, error) {
+	today := startOfDay(now.In(loc))
+
+	shortcuts := 0
+	for _, set := range []bool{*a.lastMonth, *a.thisWeek, *a.lastWeek, *a.days > 0} {
+		if set {
+			shortcuts++
+		}
+	}
+	if shortcuts > 1 || (shortcuts == 1 && (*a.from != "" || *a.to != "")) {
+		return DateRange{}, errors.New("only one of --from/--to, --last-month, --this-week, --last-week or --days can be used")
+	}
+	if *a.days < 0 {
+		return DateRange{}, fmt.Errorf("--days must be positive, got %d", *a.days)
+	}
+
+	switch {
+	case *a.lastMonth:
+		first := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, loc)
+		return DateRange{From: first.AddDate(0, -1, 0), To: first.AddDate(0, 0, -1)}, nil
+	case *a.thisWeek:
+		return DateRange{From: startOfWeek(today), To: today}, nil
+	case *a.lastWeek:
+		monday := startOfWeek(today).AddDate(0, 0, -7)
+		return DateRange{From: monday, To: monday.AddDate(0, 0, 6)}, nil
+	case *a.days > 0:
+		return DateRange{From: today.AddDate(0, 0, 1-*a.days), To: today}, nil
+	}
+	return explicitRange(*a.from, *a.to, today, loc)
+}
+
+// explicitRange resolves --from and --to. A missing --to means today and a
+// missing --from means the first of the month --to falls in.
+func explicitRange(from, to string, today time.Time, loc *time.Location) (DateRange, error) {
+	r := DateRange{To: today}
+	var err error
+	if to != "" {
+		if r.To, err = time.ParseInLocation(DateLayout, to, loc); err != nil {
+			return DateRange{}, fmt.Errorf("--to:%s", err)
+		}
+	}
+	r.From = time.Date(r.To.Year(), r.To.Month(), 1, 0, 0, 0, 0, loc)
+	if from != "" {
+		if r.From, err = time.ParseInLocation(DateLayout, from, loc); err != nil {
+			return DateRange{}, fmt.Errorf("--from:%s", err)
+		}
+	}
+	if r.From.After(r.To) {
+		return DateRange{}, fmt.Errorf("--from %s is after --to %s", r.From.Format(DateLayout), r.To.Format(DateLayout))
+	}
+	return r, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// startOfWeek returns the Monday of the ISO week t falls in.
+func startOfWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return startOfDay(t).AddDate(0, 0, -offset)
+}
@@ -0,0 +1,61 @@
+package shared
+
+import (
+	"testing"
+	"time"
+)
+
+func testDateRangeArgs(from, to string, lastMonth, thisWeek, lastWeek bool, days int) *DateRangeArgs {
+	return &DateRangeArgs{
+		from:      &from,
+		to:        &to,
+		lastMonth: &lastMonth,
+		thisWeek:  &thisWeek,
+		lastWeek:  &lastWeek,
+		days:      &days,
+	}
+}
+
+func TestDateRangeArgs_Range(t *testing.T) {
+	loc, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %s", err)
+	}
+	// Already Thursday in UTC but still Wednesday evening in Chicago, to make
+	// sure "today" is taken from the location passed in.
+	now := time.Date(2020, time.October, 15, 3, 30, 0, 0, time.UTC)
+
+	var tests = []struct {
+		name string
+		args *DateRangeArgs
+		want string
+	}{
+		{"default", testDateRangeArgs("", "", false, false, false, 0), "2020-10-01 to 2020-10-14"},
+		{"last-month", testDateRangeArgs("", "", true, false, false, 0), "2020-09-01 to 2020-09-30"},
+		{"this-week", testDateRangeArgs("", "", false, true, false, 0), "2020-10-12 to 2020-10-14"},
+		{"last-week", testDateRangeArgs("", "", false, false, true, 0), "2020-10-05 to 2020-10-11"},
+		{"days", testDateRangeArgs("", "", false, false, false, 3), "2020-10-12 to 2020-10-14"},
+		{"from", testDateRangeArgs("2020-09-20", "", false, false, false, 0), "2020-09-20 to 2020-10-14"},
+		{"to", testDateRangeArgs("", "2020-08-12", false, false, false, 0), "2020-08-01 to 2020-08-12"},
+	}
+	for _, tc := range tests {
+		r, err := tc.args.Range(now, loc)
+		if err != nil {
+			t.Fatalf("ERROR: %s:%s", tc.name, err)
+		}
+		if r.String() != tc.want {
+			t.Errorf("ERROR: %s resolved to %s, expected %s", tc.name, r, tc.want)
+		}
+	}
+
+	for name, args := range map[string]*DateRangeArgs{
+		"conflict":  testDateRangeArgs("2020-10-01", "", true, false, false, 0),
+		"reversed":  testDateRangeArgs("2020-10-10", "2020-10-01", false, false, false, 0),
+		"malformed": testDateRangeArgs("10/01/2020", "", false, false, false, 0),
+	} {
+		if _, err := args.Range(now, loc); err == nil {
+			t.Errorf("ERROR: %s should not resolve", name)
+		}
+	}
+	t.Logf("SUCCESS: resolved %d date ranges", len(tests))
+}
@@ -0,0 +1,52 @@
+package shared
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDuration parses a duration the way people write time spent, e.g. "1h30m",
+// "1h 30m", "1.5h", "90m" or "1:30", rounded to the minute. A bare number is
+// rejected rather than guessing whether it means hours or minutes.
+func ParseDuration(s string) (time.Duration, error) {
+	raw := strings.ToLower(strings.Join(strings.Fields(s), ""))
+	if raw == "" {
+		return 0, fmt.Errorf("shared.ParseDuration:no duration given")
+	}
+
+	var (
+		d   time.Duration
+		err error
+	)
+	if parts := strings.SplitN(raw, ":", 2); len(parts) == 2 {
+		d, err = parseClock(parts[0], parts[1])
+	} else if _, numErr := strconv.ParseFloat(raw, 64); numErr == nil {
+		return 0, fmt.Errorf("shared.ParseDuration:%q has no unit, use e.g. %sm or %sh", s, raw, raw)
+	} else {
+		d, err = time.ParseDuration(raw)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("shared.ParseDuration:%q is not a duration, use e.g. 1h30m, 1.5h, 90m or 1:30", s)
+	}
+
+	d = d.Round(time.Minute)
+	if d < time.Minute {
+		return 0, fmt.Errorf("shared.ParseDuration:%q is less than a minute", s)
+	}
+	return d, nil
+}
+
+// parseClock parses the hours and minutes of a h:mm duration.
+func parseClock(h, m string) (time.Duration, error) {
+	hours, err := strconv.Atoi(h)
+	if err != nil || hours < 0 {
+		return 0, fmt.Errorf("invalid hours %q", h)
+	}
+	minutes, err := strconv.Atoi(m)
+	if err != nil || minutes < 0 || minutes > 59 || len(m) != 2 {
+		return 0, fmt.Errorf("invalid minutes %q", m)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
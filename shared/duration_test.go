@@ -0,0 +1,35 @@
+package shared
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	var tests = []struct {
+		in   string
+		want time.Duration
+	}{
+		{"1h30m", 90 * time.Minute},
+		{"1h 30m", 90 * time.Minute},
+		{"1.5h", 90 * time.Minute},
+		{"90m", 90 * time.Minute},
+		{"1:30", 90 * time.Minute},
+		{"0:05", 5 * time.Minute},
+		{"2H", 2 * time.Hour},
+		{"15m20s", 15 * time.Minute},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ERROR: ParseDuration(%q) = %s, %v, expected %s", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "90", "1.5", "abc", "1:75", "1:5", "20s", "-1h"} {
+		if got, err := ParseDuration(bad); err == nil {
+			t.Errorf("ERROR: expected ParseDuration(%q) to fail, got %s", bad, got)
+		}
+	}
+	t.Logf("SUCCESS: parsed %d durations", len(tests))
+}
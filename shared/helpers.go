@@ -3,7 +3,7 @@ package shared
 import (
 	"bufio"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"sort"
 	"strconv"
@@ -56,22 +56,46 @@ func Truncate(str string, max int) string {
 	return bnoden
 }
 
-// ConfirmPrompt is a help function to prompt the user for a confirmation.
-func ConfirmPrompt(s string) bool {
-	reader := bufio.NewReader(os.Stdin)
+// FormatMinutes formats a number of minutes as hours and minutes, e.g. "1h 30m".
+// It matches the Column.Format signature so it can be set on a report column.
+func FormatMinutes(v interface{}) string {
+	minutes, ok := v.(int)
+	if !ok {
+		return fmt.Sprint(v)
+	}
+	return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+}
+
+// ConfirmPrompt is a help function to prompt the user for a confirmation. It asks
+// again until the answer is yes or no, and fails when the input runs out.
+func ConfirmPrompt(in io.Reader, out io.Writer, s string) (bool, error) {
+	reader := bufio.NewReader(in)
 	for {
-		fmt.Printf("%s [y/n]: ", s)
+		fmt.Fprintf(out, "%s [y/n]: ", s)
 		response, err := reader.ReadString('\n')
-		if err != nil {
-			log.Fatal(err)
-		}
 		response = strings.ToLower(strings.TrimSpace(response))
 		if response == "y" || response == "yes" {
-			return true
+			return true, nil
 		} else if response == "n" || response == "no" {
-			return false
+			return false, nil
 		}
+		if err == io.EOF {
+			return false, fmt.Errorf("shared.ConfirmPrompt:%w", io.ErrUnexpectedEOF)
+		} else if err != nil {
+			return false, fmt.Errorf("shared.ConfirmPrompt:%w", err)
+		}
+	}
+}
+
+// IsTerminal reports whether the input is an interactive terminal, so prompts
+// are only shown to someone who can answer them.
+func IsTerminal(in io.Reader) bool {
+	f, ok := in.(*os.File)
+	if !ok {
+		return false
 	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
 }
 
 // ConcatVLANs function will take a list of int vlan numbers, sort the data, then
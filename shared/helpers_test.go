@@ -17,3 +17,21 @@ func TestConcatVLANs(t *testing.T) {
 	}
 	t.Logf("SUCCESS: found expected value of %s", expected)
 }
+
+func TestConfirmPrompt(t *testing.T) {
+	var out strings.Builder
+	ok, err := ConfirmPrompt(strings.NewReader("maybe\nYes\n"), &out, "Log 1h?")
+	if err != nil || !ok {
+		t.Fatalf("ERROR: expected a yes, got %v, %v", ok, err)
+	}
+	if strings.Count(out.String(), "Log 1h? [y/n]: ") != 2 {
+		t.Fatalf("ERROR: expected to be asked twice, got %q", out.String())
+	}
+	if ok, err := ConfirmPrompt(strings.NewReader("n"), &out, "Log 1h?"); err != nil || ok {
+		t.Fatalf("ERROR: expected a no without a trailing newline, got %v, %v", ok, err)
+	}
+	if _, err := ConfirmPrompt(strings.NewReader(""), &out, "Log 1h?"); err == nil {
+		t.Fatalf("ERROR: expected an error once the input runs out")
+	}
+	t.Logf("SUCCESS: %q", out.String())
+}
@@ -0,0 +1,299 @@
+package shared
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/table"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is an output format accepted by the global --output flag.
+type Format string
+
+const (
+	// FormatTable renders a human readable table, the default.
+	FormatTable Format = "table"
+	// FormatJSON renders the report as a JSON document.
+	FormatJSON Format = "json"
+	// FormatYAML renders the report as a YAML document.
+	FormatYAML Format = "yaml"
+	// FormatCSV renders the rows as CSV with a header line.
+	FormatCSV Format = "csv"
+	// FormatMarkdown renders a table in GitHub flavored markdown.
+	FormatMarkdown Format = "markdown"
+)
+
+// Formats lists every output format, in the order they are offered on the
+// command line.
+var Formats = []string{
+	string(FormatTable),
+	string(FormatJSON),
+	string(FormatYAML),
+	string(FormatCSV),
+	string(FormatMarkdown),
+}
+
+type (
+	// Column describes a single column of a Report. Name is the header shown
+	// in tables, Key is the field name used in structured output and
+	// defaults to the snake cased Name. Format, if set, converts the raw
+	// value for the human readable formats only, so that structured output
+	// keeps numbers as numbers. CSV, which has no lists, formats lists with
+	// it too, and lists without a Format are joined with ", ". Hidden
+	// columns only appear in structured output (JSON, YAML and CSV).
+	Column struct {
+		Name   string
+		Key    string
+		Format func(v interface{}) string
+		Hidden bool
+	}
+
+	// Label is a cell value that is only shown in the human readable formats,
+	// such as the "Subtotal" marker of a grouped report. Structured output
+	// leaves it out.
+	Label string
+
+	// Report is the structured data a plugin hands to Render. Rows hold the
+	// raw values in the same order as Columns. Notes are shown below tables
+	// and included in structured output, but left out of CSV.
+	Report struct {
+		Title   string
+		Columns []Column
+		Rows    [][]interface{}
+		Footer  []interface{}
+		Notes   []string
+	}
+
+	// record is a single row keyed by column, marshalled in column order.
+	record struct {
+		keys   []string
+		values []interface{}
+	}
+)
+
+// Render writes the report to w in the format passed in.
+func Render(w io.Writer, f Format, r Report) error {
+	switch f {
+	case FormatTable, "":
+		r.table(w).Render()
+		return nil
+	case FormatMarkdown:
+		r.table(w).RenderMarkdown()
+		return nil
+	case FormatCSV:
+		return r.csv(w)
+	case FormatJSON, FormatYAML:
+		return Encode(w, f, r.document())
+	}
+	return fmt.Errorf("render: unknown output format %q", f)
+}
+
+// Encode writes v as a JSON or YAML document, for output that doesn't fit the
+// rows of a Report.
+func Encode(w io.Writer, f Format, v interface{}) error {
+	switch f {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	return fmt.Errorf("render: %q can't encode a document, use json or yaml", f)
+}
+
+// key returns the structured field name of the column.
+func (c Column) key() string {
+	if c.Key != "" {
+		return c.Key
+	}
+	return strings.Replace(strings.ToLower(strings.TrimSpace(c.Name)), " ", "_", -1)
+}
+
+// text returns the human readable form of a value in this column.
+func (c Column) text(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	if c.Format != nil {
+		return c.Format(v)
+	}
+	return join(v)
+}
+
+// join writes the items of a list separated by commas, and anything else as is.
+func join(v interface{}) string {
+	if !isList(v) {
+		return fmt.Sprint(v)
+	}
+	list := reflect.ValueOf(v)
+	items := make([]string, list.Len())
+	for i := range items {
+		items[i] = fmt.Sprint(list.Index(i).Interface())
+	}
+	return strings.Join(items, ", ")
+}
+
+func (r Report) table(w io.Writer) table.Writer {
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.SetTitle(r.Title)
+
+	header := make(table.Row, 0, len(r.Columns))
+	for _, c := range r.Columns {
+		if !c.Hidden {
+			header = append(header, c.Name)
+		}
+	}
+	t.AppendHeader(header)
+	for _, row := range r.Rows {
+		t.AppendRow(r.textRow(row))
+	}
+	if r.Footer != nil {
+		t.AppendFooter(r.textRow(r.Footer))
+	}
+	t.SetCaption(strings.Join(r.Notes, "\n"))
+	t.SetStyle(table.StyleDefault)
+	return t
+}
+
+// textRow formats a row for the human readable formats. Strings are passed
+// through as is, so labels such as "Total" can sit in any column.
+func (r Report) textRow(values []interface{}) table.Row {
+	row := make(table.Row, 0, len(values))
+	for i, v := range values {
+		if i < len(r.Columns) {
+			if r.Columns[i].Hidden {
+				continue
+			}
+			switch v.(type) {
+			case string, Label:
+			default:
+				v = r.Columns[i].text(v)
+			}
+		}
+		row = append(row, v)
+	}
+	return row
+}
+
+func (r Report) csv(w io.Writer) error {
+	out := csv.NewWriter(w)
+	keys := make([]string, len(r.Columns))
+	for i, c := range r.Columns {
+		keys[i] = c.key()
+	}
+	if err := out.Write(keys); err != nil {
+		return err
+	}
+	for _, row := range r.Rows {
+		line := make([]string, len(row))
+		for i, v := range row {
+			switch value := v.(type) {
+			case nil, Label:
+			case time.Time:
+				line[i] = value.Format(time.RFC3339)
+			default:
+				if i < len(r.Columns) && isList(value) {
+					line[i] = r.Columns[i].text(value)
+				} else {
+					line[i] = fmt.Sprint(value)
+				}
+			}
+		}
+		if err := out.Write(line); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// isList reports whether v is a slice or an array, which fmt would print in brackets.
+func isList(v interface{}) bool {
+	kind := reflect.ValueOf(v).Kind()
+	return kind == reflect.Slice || kind == reflect.Array
+}
+
+// document builds the structured form of the report shared by JSON and YAML.
+func (r Report) document() interface{} {
+	rows := make([]record, 0, len(r.Rows))
+	for _, row := range r.Rows {
+		rows = append(rows, r.record(row))
+	}
+	doc := record{keys: []string{"title", "rows"}, values: []interface{}{r.Title, rows}}
+	if r.Footer != nil {
+		doc.keys = append(doc.keys, "footer")
+		doc.values = append(doc.values, r.record(r.Footer))
+	}
+	if len(r.Notes) > 0 {
+		doc.keys = append(doc.keys, "notes")
+		doc.values = append(doc.values, r.Notes)
+	}
+	return doc
+}
+
+// record keys a row by column, leaving out empty cells.
+func (r Report) record(row []interface{}) record {
+	rec := record{}
+	for i, v := range row {
+		if _, label := v.(Label); label || i >= len(r.Columns) || v == nil || v == "" {
+			continue
+		}
+		rec.keys = append(rec.keys, r.Columns[i].key())
+		rec.values = append(rec.values, v)
+	}
+	return rec
+}
+
+// MarshalJSON writes the record as an object, keeping the column order.
+func (r record) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range r.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(r.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// MarshalYAML writes the record as a mapping, keeping the column order.
+func (r record) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for i, k := range r.keys {
+		var key, value yaml.Node
+		if err := key.Encode(k); err != nil {
+			return nil, err
+		}
+		if err := value.Encode(r.values[i]); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &key, &value)
+	}
+	return node, nil
+}
@@ -0,0 +1,73 @@
+package shared
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var testReport = Report{
+	Title: "Test Report",
+	Columns: []Column{
+		{Name: "JIRA ID"},
+		{Name: "HOURS SPENT", Key: "minutes", Format: FormatMinutes},
+	},
+	Rows: [][]interface{}{
+		{"ABC-1", 90},
+		{"ABC-2", 45},
+	},
+	Footer: []interface{}{"Total", 135},
+}
+
+func TestRender(t *testing.T) {
+	var tests = map[Format][]string{
+		FormatTable:    {"Test Report", "JIRA ID", "ABC-1", "1h 30m", "2H 15M"},
+		FormatMarkdown: {"| JIRA ID | HOURS SPENT |", "| ABC-2 | 0h 45m |"},
+		FormatCSV:      {"jira_id,minutes\nABC-1,90\nABC-2,45\n"},
+		FormatJSON:     {`"title": "Test Report"`, `"jira_id": "ABC-1",`, `"minutes": 90`, `"footer"`},
+		FormatYAML:     {"title: Test Report", "- jira_id: ABC-1\n    minutes: 90", "footer:"},
+	}
+	for format, expected := range tests {
+		var buf bytes.Buffer
+		if err := Render(&buf, format, testReport); err != nil {
+			t.Fatalf("ERROR: Render(%s):%s", format, err)
+		}
+		for _, want := range expected {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("ERROR: %s output is missing %q:\n%s", format, want, buf.String())
+			}
+		}
+	}
+	if err := Render(&bytes.Buffer{}, "xml", testReport); err == nil {
+		t.Fatal("ERROR: expected an error for an unknown format")
+	}
+	t.Logf("SUCCESS: rendered %d formats", len(tests))
+}
+
+func TestRenderLists(t *testing.T) {
+	r := Report{
+		Columns: []Column{
+			{Name: "KEY"},
+			{Name: "LABELS"},
+			{Name: "ALLOWED VALUES", Format: func(v interface{}) string { return join(v) + " (2)" }},
+		},
+		Rows: [][]interface{}{
+			{"ABC-1", []string{"backup", "network"}, []string{"High", "Low"}},
+			{"ABC-2", []string{}, nil},
+		},
+	}
+	var tests = map[Format]string{
+		FormatCSV:      "key,labels,allowed_values\nABC-1,\"backup, network\",\"High, Low (2)\"\nABC-2,,\n",
+		FormatMarkdown: "| ABC-1 | backup, network | High, Low (2) |",
+	}
+	for format, want := range tests {
+		var buf bytes.Buffer
+		if err := Render(&buf, format, r); err != nil {
+			t.Fatalf("ERROR: Render(%s):%s", format, err)
+		}
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("ERROR: %s output is missing %q:\n%s", format, want, buf.String())
+		}
+	}
+	t.Logf("SUCCESS: rendered the lists of %d formats", len(tests))
+}